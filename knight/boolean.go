@@ -2,6 +2,7 @@ package knight
 
 import (
 	"fmt"
+	"io"
 )
 
 // Boolean is the boolean type within Knight.
@@ -12,10 +13,10 @@ type Boolean bool
 // Compile-time assertion that Boolean implements the Value interface.
 var _ Value = Boolean(false)
 
-// Dump prints "true" or "false" to stdout.
-func (b Boolean) Dump() {
+// Dump writes "true" or "false" to w.
+func (b Boolean) Dump(w io.Writer) {
 	// In golang, `%t` is for booleans (just like `%d` is for ints and `%s` is for strings)
-	fmt.Printf("%t", b)
+	fmt.Fprintf(w, "%t", b)
 }
 
 // Execute simply returns the boolean unchanged.
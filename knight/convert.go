@@ -0,0 +1,185 @@
+package knight
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+//
+// The following are helper functions for embedders, to convert Go values into Values (such as for
+// use with SetVariable).
+//
+
+// FromInt returns i as an Integer.
+func FromInt(i int) Integer {
+	return Integer(i)
+}
+
+// FromString returns s as a String.
+func FromString(s string) String {
+	return String(s)
+}
+
+// FromBool returns b as a Boolean.
+func FromBool(b bool) Boolean {
+	return Boolean(b)
+}
+
+// FromSlice returns values as a List. An empty (or nil) slice becomes an empty List.
+func FromSlice(values []Value) List {
+	if len(values) == 0 {
+		return List{}
+	}
+
+	return List(values)
+}
+
+// FromGo converts a Go value into the corresponding Value. The following types are supported:
+//
+//   - `nil` becomes Null.
+//   - Values are returned unchanged.
+//   - Booleans become Booleans.
+//   - All integer types become Integers. Unsigned integers which are too large to fit in an `int`
+//     yield an error.
+//   - Strings become Strings.
+//   - Slices and arrays become Lists, with each element converted via FromGo. (So, `[]byte` becomes
+//     a List of Integers, not a String.)
+//
+// Everything else (such as floats, maps, structs, and pointers) yields an error, as Knight has no
+// equivalent for them. (Maps could become Maps, but their keys have no defined order.)
+func FromGo(value any) (Value, error) {
+	if value == nil {
+		return Null{}, nil
+	}
+
+	if value, ok := value.(Value); ok {
+		return value, nil
+	}
+
+	reflected := reflect.ValueOf(value)
+
+	switch reflected.Kind() {
+	case reflect.Bool:
+		return Boolean(reflected.Bool()), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Integer(reflected.Int()), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if reflected.Uint() > math.MaxInt {
+			return nil, fmt.Errorf("integer too large to convert to a Value: %d", reflected.Uint())
+		}
+
+		return Integer(reflected.Uint()), nil
+
+	case reflect.String:
+		return String(reflected.String()), nil
+
+	case reflect.Slice, reflect.Array:
+		list := make(List, reflected.Len())
+
+		for i := range list {
+			element, err := FromGo(reflected.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			list[i] = element
+		}
+
+		return list, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported type to convert to a Value: %T", value)
+	}
+}
+
+//
+// The following are helper functions for embedders, to convert Values into Go values (such as the
+// result of Evaluate). Unlike the Value interface's conversion methods, they can be given nil.
+//
+
+// errNilValue is returned by the As functions when they're given nil.
+var errNilValue = errors.New("can't convert a nil Value")
+
+// AsInt converts value to an int via Value.ToInt. It returns an error if value is nil.
+func AsInt(value Value) (int, error) {
+	if value == nil {
+		return 0, errNilValue
+	}
+
+	return value.ToInt()
+}
+
+// AsString converts value to a string via Value.ToString. It returns an error if value is nil.
+func AsString(value Value) (string, error) {
+	if value == nil {
+		return "", errNilValue
+	}
+
+	return value.ToString()
+}
+
+// AsBool converts value to a bool via Value.ToBool. It returns an error if value is nil.
+func AsBool(value Value) (bool, error) {
+	if value == nil {
+		return false, errNilValue
+	}
+
+	return value.ToBool()
+}
+
+// AsSlice converts value to a slice via Value.ToSlice. It returns an error if value is nil.
+func AsSlice(value Value) ([]Value, error) {
+	if value == nil {
+		return nil, errNilValue
+	}
+
+	return value.ToSlice()
+}
+
+// IntoGo returns the closest Go equivalent of value, without any coercions. It's roughly the
+// inverse of FromGo:
+//
+//   - Integers become `int`s, Strings become `string`s, and Booleans become `bool`s.
+//   - Null (and nil) become `nil`.
+//   - Lists become `[]any`s, with each element converted via IntoGo.
+//   - Maps become `map[string]any`s, with each value converted via IntoGo.
+//
+// Everything else (ie Variables and FnCalls, from `BLOCK`) is returned unchanged.
+func IntoGo(value Value) any {
+	switch value := value.(type) {
+	case nil, Null:
+		return nil
+
+	case Integer:
+		return int(value)
+
+	case String:
+		return string(value)
+
+	case Boolean:
+		return bool(value)
+
+	case List:
+		slice := make([]any, len(value))
+		for i, element := range value {
+			slice[i] = IntoGo(element)
+		}
+
+		return slice
+
+	case Map:
+		converted := make(map[string]any, len(value.keys))
+		for _, key := range value.keys {
+			converted[key] = IntoGo(value.values[key])
+		}
+
+		return converted
+
+	default:
+		return value
+	}
+}
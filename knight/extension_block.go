@@ -0,0 +1,257 @@
+package knight
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Register the block extension functions. (See `init` in `function.go` for more details.)
+func init() {
+	ExtensionFunctions["XINFIX"] = &Function{name: "XINFIX", arity: 1, fn: infix}
+	ExtensionFunctions["XCOMPOSE"] = &Function{name: "XCOMPOSE", arity: 2, fn: compose}
+	ExtensionFunctions["XBLOCKNAME"] = &Function{name: "XBLOCKNAME", arity: 1, fn: blockName}
+	ExtensionFunctions["XBLOCKARITY"] = &Function{name: "XBLOCKARITY", arity: 1, fn: blockArity}
+}
+
+// infixOperators are the precedences of the functions which `XINFIX` renders as infix operators.
+// Higher numbers bind more tightly. The unary operators `~` and `!` bind more tightly than all of
+// them.
+var infixOperators = map[string]int{
+	"|": 1,
+	"&": 2,
+	"?": 3,
+	"<": 4, ">": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+	"^": 7,
+}
+
+// unaryPrecedence is the precedence of the unary operators (`~` and `!`) within `XINFIX`.
+const unaryPrecedence = 8
+
+// infix executes its argument, and then returns a string representation of the result in
+// conventional infix notation. This is intended to be used with `BLOCK`, to show what code does in
+// a more familiar form.
+//
+// The following functions are rendered as operators; all other functions are rendered in function
+// call notation (eg `OUTPUT(x)`), except for ones that don't take arguments (eg `TRUE`):
+//
+//   - `~` as `-x` and `!` as `!x`, which bind the most tightly.
+//   - `^`, which is right-associative (so `2 ^ 3 ^ 2` is `2 ^ (3 ^ 2)`).
+//   - `*`, `/`, and `%`.
+//   - `+` and `-`.
+//   - `<` and `>`.
+//   - `?`, which is rendered as `==`.
+//   - `&`.
+//   - `|`, which binds the least tightly.
+//
+// Each group above binds more tightly than the groups below it, and all of the binary operators
+// besides `^` are left-associative. Parentheses are only added when they're needed for the result
+// to have the same structure as the code. Strings are rendered the same way `DUMP` renders them,
+// and variables are rendered as their name.
+//
+// ## Examples
+//
+//	OUTPUT XINFIX BLOCK + 1 * 2 3          #=> 1 + 2 * 3
+//	OUTPUT XINFIX BLOCK * + 1 2 3          #=> (1 + 2) * 3
+//	OUTPUT XINFIX BLOCK - 1 - 2 3          #=> 1 - (2 - 3)
+//	OUTPUT XINFIX BLOCK & (? a 1) ~ b      #=> a == 1 & -b
+//	OUTPUT XINFIX BLOCK OUTPUT + "a" TRUE  #=> OUTPUT("a" + TRUE)
+//	OUTPUT XINFIX 12                       #=> 12
+func infix(args []Value) (Value, error) {
+	value, err := args[0].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	var builder strings.Builder
+	writeInfix(&builder, value)
+	return String(builder.String()), nil
+}
+
+// writeInfix is the recursive helper function for infix; it writes value in infix notation to
+// builder.
+func writeInfix(builder *strings.Builder, value Value) {
+	switch value := value.(type) {
+	case *Variable:
+		builder.WriteString(value.name)
+
+	case *FnCall:
+		name := value.function.name
+
+		if precedence, ok := infixOperators[name]; ok {
+			// For left-associative operators, the right-hand side needs parentheses if it has the same
+			// precedence; for right-associative ones (ie `^`) it's the left-hand side.
+			lhsPrecedence, rhsPrecedence := precedence, precedence+1
+			if name == "^" {
+				lhsPrecedence, rhsPrecedence = precedence+1, precedence
+			}
+
+			if name == "?" {
+				name = "=="
+			}
+
+			writeInfixOperand(builder, value.arguments[0], lhsPrecedence)
+			builder.WriteString(" " + name + " ")
+			writeInfixOperand(builder, value.arguments[1], rhsPrecedence)
+			return
+		}
+
+		if name == "~" || name == "!" {
+			if name == "~" {
+				name = "-"
+			}
+
+			builder.WriteString(name)
+			writeInfixOperand(builder, value.arguments[0], unaryPrecedence)
+			return
+		}
+
+		builder.WriteString(name)
+		if len(value.arguments) == 0 {
+			return
+		}
+
+		builder.WriteString("(")
+		for i, argument := range value.arguments {
+			if i != 0 {
+				builder.WriteString(", ")
+			}
+
+			writeInfix(builder, argument)
+		}
+		builder.WriteString(")")
+
+	default:
+		value.Dump(builder)
+	}
+}
+
+// writeInfixOperand writes operand in infix notation to builder, surrounding it with parentheses if
+// it's an operator which binds less tightly than minimumPrecedence.
+func writeInfixOperand(builder *strings.Builder, operand Value, minimumPrecedence int) {
+	precedence := unaryPrecedence + 1 // everything that's not an operator never needs parentheses.
+
+	if fnCall, ok := operand.(*FnCall); ok {
+		if operatorPrecedence, ok := infixOperators[fnCall.function.name]; ok {
+			precedence = operatorPrecedence
+		} else if fnCall.function.name == "~" || fnCall.function.name == "!" {
+			precedence = unaryPrecedence
+		}
+	}
+
+	if precedence < minimumPrecedence {
+		builder.WriteString("(")
+		writeInfix(builder, operand)
+		builder.WriteString(")")
+	} else {
+		writeInfix(builder, operand)
+	}
+}
+
+// compose executes both its arguments, which should be blocks `f` and `g`, and returns a new block
+// which, when `CALL`ed, calls `g`, assigns its result to the variable `_1`, and then calls `f` and
+// returns its result. So, `f` can use `_1` to get `g`'s result. (Since `_1` is an ordinary global
+// variable, it's left assigned afterwards.)
+//
+// The returned block is the same as `BLOCK ; (= _1 CALL g) CALL f` would be, except that `f` and
+// `g` are the blocks `XCOMPOSE` was given, rather than whatever the variables `f` and `g` happen to
+// be when it's called. It's constructed via NewFnCall, and can be `DUMP`ed to see what it does.
+//
+// ## Examples
+//
+//	; = double BLOCK * _1 2
+//	; = five BLOCK 5
+//	: DUMP CALL XCOMPOSE double five                      #=> 10
+//
+//	; = inc BLOCK + _1 1
+//	; = _1 3
+//	: DUMP CALL XCOMPOSE inc (XCOMPOSE inc BLOCK _1)     #=> 5
+//
+// ## Undefined Behaviour
+// As with `CALL`, arguments which aren't blocks are just returned when the composed block is
+// called:
+//
+//	DUMP CALL XCOMPOSE (BLOCK _1) 3                       #=> 3
+func compose(args []Value) (Value, error) {
+	f, err := args[0].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := args[1].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	// Executing a block's value is what `CALL` does, so we can use the blocks directly, rather than
+	// wrapping them in `CALL`s.
+	assignment := NewFnCall(KnownFunctions['='], []Value{NewVariable("_1"), g})
+	return NewFnCall(KnownFunctions[';'], []Value{assignment, f}), nil
+}
+
+// executeToFnCall is a helper function which executes value and returns an error if it's not a
+// function call (ie a block of code that's a function call, such as `BLOCK + 1 2`). The
+// functionName argument is just used for error messages.
+func executeToFnCall(value Value, functionName string) (*FnCall, error) {
+	ran, err := value.Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	fnCall, ok := ran.(*FnCall)
+	if !ok {
+		return nil, fmt.Errorf("invalid type given to '%s': %T", functionName, ran)
+	}
+
+	return fnCall, nil
+}
+
+// blockName executes its argument, which should be a block, and returns the full name of the
+// function the block calls, without calling it.
+//
+// Blocks of just a literal or a variable (eg `BLOCK 3` or `BLOCK a`) don't call a function, so
+// they yield an error. (`BLOCK 3` is indistinguishable from `3` itself.)
+//
+// ## Examples
+//
+//	DUMP XBLOCKNAME BLOCK + 1 2               #=> "+"
+//	DUMP XBLOCKNAME BLOCK D 3                 #=> "DUMP"
+//	; = b BLOCK OUTPUT 1 : DUMP XBLOCKNAME b  #=> "OUTPUT"
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XBLOCKNAME` yield errors:
+//
+//	DUMP XBLOCKNAME BLOCK 3                   #!! error: invalid type
+//	DUMP XBLOCKNAME BLOCK a                   #!! error: invalid type
+func blockName(args []Value) (Value, error) {
+	fnCall, err := executeToFnCall(args[0], "XBLOCKNAME")
+	if err != nil {
+		return nil, err
+	}
+
+	return String(fnCall.function.name), nil
+}
+
+// blockArity executes its argument, which should be a block, and returns the amount of arguments
+// the function the block calls takes, without calling it. Like `XBLOCKNAME`, it yields an error
+// for blocks of just a literal or a variable.
+//
+// ## Examples
+//
+//	DUMP XBLOCKARITY BLOCK + 1 2              #=> 2
+//	DUMP XBLOCKARITY BLOCK TRUE               #=> 0
+//	DUMP XBLOCKARITY BLOCK SET @ 0 0 @        #=> 4
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XBLOCKARITY` yield errors:
+//
+//	DUMP XBLOCKARITY BLOCK 3                  #!! error: invalid type
+func blockArity(args []Value) (Value, error) {
+	fnCall, err := executeToFnCall(args[0], "XBLOCKARITY")
+	if err != nil {
+		return nil, err
+	}
+
+	return Integer(fnCall.function.arity), nil
+}
@@ -0,0 +1,21 @@
+package knight
+
+import "testing"
+
+func TestInfix(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{`XINFIX BLOCK + 1 * 2 3`, String("1 + 2 * 3")},
+		{`XINFIX BLOCK * + 1 2 3`, String("(1 + 2) * 3")},
+		{`XINFIX BLOCK - 1 - 2 3`, String("1 - (2 - 3)")},
+		{`XINFIX BLOCK - - 1 2 3`, String("1 - 2 - 3")},
+		{`XINFIX BLOCK ^ 2 ^ 3 2`, String("2 ^ 3 ^ 2")},
+		{`XINFIX BLOCK ^ ^ 2 3 2`, String("(2 ^ 3) ^ 2")},
+		{`XINFIX BLOCK ~ + 1 2`, String("-(1 + 2)")},
+		{`XINFIX BLOCK & (? a 1) ~ b`, String("a == 1 & -b")},
+		{`XINFIX BLOCK OUTPUT + "a" TRUE`, String(`OUTPUT("a" + TRUE)`)},
+		{`XINFIX BLOCK IF a 1 2`, String("IF(a, 1, 2)")},
+		{`XINFIX BLOCK * LENGTH + a b 2`, String("LENGTH(a + b) * 2")},
+		{`XINFIX 12`, String("12")},
+		{`XINFIX "a"`, String(`"a"`)},
+	})
+}
@@ -0,0 +1,617 @@
+package knight
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// TimeLimitExceeded is returned when code run by `XTIMEOUT` takes too long.
+var TimeLimitExceeded = errors.New("time limit exceeded")
+
+// AbortError is the error returned by `XABORT`. Its Message is the string `XABORT` was given.
+type AbortError struct {
+	Message string
+}
+
+// Error returns the message the AbortError was created with.
+func (e *AbortError) Error() string {
+	return e.Message
+}
+
+// catchable returns whether err can be caught by extension functions which handle errors (such as
+// `XTRY` and `XRETRY`). *QuitErrors can't be, as `QUIT` should always stop the program.
+//
+// TimeLimitExceeded can only be caught if it came from an `XTIMEOUT` that's already finished (ie
+// one within the code being run). If the deadline of an `XTIMEOUT` that's still running has passed,
+// it can't be, as otherwise code could ignore the time limit it's running within.
+func catchable(err error) bool {
+	var quitError *QuitError
+	if errors.As(err, &quitError) {
+		return false
+	}
+
+	if errors.Is(err, TimeLimitExceeded) {
+		return checkDeadline() == nil
+	}
+
+	return true
+}
+
+// deadline is when the innermost `XTIMEOUT` that's currently running expires. It's the zero time
+// when there's no `XTIMEOUT` running.
+var deadline time.Time
+
+// checkDeadline returns TimeLimitExceeded if the current `XTIMEOUT`'s deadline has passed. It's
+// called before every function call, and on every iteration of `WHILE`.
+func checkDeadline() error {
+	if !deadline.IsZero() && Now().After(deadline) {
+		return TimeLimitExceeded
+	}
+
+	return nil
+}
+
+// sleep pauses for duration via Sleep, but no later than the current `XTIMEOUT`'s deadline. If the
+// deadline passes, TimeLimitExceeded is returned. (Negative durations don't sleep at all.)
+func sleep(duration time.Duration) error {
+	if !deadline.IsZero() {
+		if remaining := deadline.Sub(Now()); remaining < duration {
+			Sleep(remaining)
+			return TimeLimitExceeded
+		}
+	}
+
+	Sleep(duration)
+	return checkDeadline()
+}
+
+// timestampMutex protects the timestamp of every FnCall, which is when an `XTHROTTLE` last executed
+// its body, or when an `XDEBOUNCE` was last called. (As it's stored on the function call itself,
+// it's discarded along with it, such as when code run via `EVAL` is finished.) The same function
+// call can be executed from multiple goroutines at once (see ConcurrentVariables), so it's always
+// locked, although only while the timestamp is being accessed, and not while the body executes.
+var timestampMutex sync.Mutex
+
+// Register the control flow and variable extension functions. (See `init` in `function.go` for more
+// details.)
+func init() {
+	ExtensionFunctions["XEXCHANGE"] = &Function{name: "XEXCHANGE", arity: 2, fn: exchange}
+	ExtensionFunctions["XLET"] = &Function{name: "XLET", arity: 3, fn: let}
+	ExtensionFunctions["XTIMEOUT"] = &Function{name: "XTIMEOUT", arity: 2, fn: timeout}
+	ExtensionFunctions["XRETRY"] = &Function{name: "XRETRY", arity: 3, fn: retry}
+	ExtensionFunctions["XTHROTTLE"] = &Function{name: "XTHROTTLE", arity: 2, call: throttle}
+	ExtensionFunctions["XDEBOUNCE"] = &Function{name: "XDEBOUNCE", arity: 2, call: debounce}
+	ExtensionFunctions["XDOWHILE"] = &Function{name: "XDOWHILE", arity: 2, fn: doWhile}
+	ExtensionFunctions["XFOREACH"] = &Function{name: "XFOREACH", arity: 3, fn: forEach}
+	ExtensionFunctions["XABORT"] = &Function{name: "XABORT", arity: 1, fn: abort}
+	ExtensionFunctions["XTRY"] = &Function{name: "XTRY", arity: 2, fn: try}
+	ExtensionFunctions["XREPEAT"] = &Function{name: "XREPEAT", arity: 2, fn: repeat}
+	ExtensionFunctions["XASSERT"] = &Function{name: "XASSERT", arity: 2, fn: assert}
+	ExtensionFunctions["XTIME"] = &Function{name: "XTIME", arity: 1, fn: timeExecution}
+}
+
+// exchange is like `=`, except it returns the variable's previous value instead of the new one. If
+// the variable was previously unassigned, Null is returned. Like `=`, the first argument must be a
+// Variable, or an error is returned.
+//
+// ## Examples
+//
+//	; = a 1 : DUMP XEXCHANGE a 2                       #=> 1
+//	DUMP XEXCHANGE unassigned 2                        #=> null
+//	; = a 1 ; = b 2 ; = a XEXCHANGE b a : DUMP +,a ,b  #=> [2, 1]  (swaps `a` and `b`)
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XEXCHANGE` yield errors:
+//
+//	XEXCHANGE 12 34 #!! error: can only assign variables
+func exchange(args []Value) (Value, error) {
+	variable, ok := args[0].(*Variable)
+	if !ok {
+		return nil, fmt.Errorf("invalid type given to 'XEXCHANGE': %T", args[0])
+	}
+
+	value, err := args[1].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	// An unassigned variable has a `nil` value; see `Variable` for details.
+	previous := variable.load()
+	if previous == nil {
+		previous = Null{}
+	}
+
+	variable.Assign(value)
+
+	return previous, nil
+}
+
+// let temporarily assigns the second argument to the variable given as the first argument, executes
+// the third argument, and then restores the variable to its previous value (or unassigns it, if it
+// was unassigned before), returning the third argument's result. Like `=`, the first argument must
+// be a Variable, or an error is returned.
+//
+// The variable is restored even if executing the third argument returns an error.
+//
+// Since Knight only has global variables, this gives "dynamic scoping": Everything executed by the
+// third argument (including `CALL`ed blocks) sees the temporary value.
+//
+// ## Examples
+//
+//	; = a 1 ; DUMP XLET a 2 a : DUMP a                   #=> 21
+//	; = b BLOCK * a 10 : DUMP XLET a 3 CALL b            #=> 30
+//	; XLET unassigned 1 NULL : DUMP unassigned           #!! error: undefined variable
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XLET` yield errors:
+//
+//	XLET 12 34 56 #!! error: can only assign variables
+func let(args []Value) (Value, error) {
+	variable, ok := args[0].(*Variable)
+	if !ok {
+		return nil, fmt.Errorf("invalid type given to 'XLET': %T", args[0])
+	}
+
+	value, err := args[1].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	// We restore the value via `store`, as `Assign` doesn't allow unassigning variables.
+	previous := variable.load()
+	variable.Assign(value)
+	defer variable.store(previous)
+
+	return args[2].Execute()
+}
+
+// millisecondsToDuration converts milliseconds to a Duration. Amounts too large to fit in one (ie
+// more than about 292 years) become the largest Duration, rather than overflowing into negative or
+// nonsensical ones.
+func millisecondsToDuration(milliseconds int) time.Duration {
+	if int64(math.MaxInt64/time.Millisecond) < int64(milliseconds) {
+		return math.MaxInt64
+	}
+
+	return time.Duration(milliseconds) * time.Millisecond
+}
+
+// timeout converts its first argument to an integer, and then executes the second argument,
+// returning its result. If executing the second argument takes longer than that many milliseconds,
+// it's stopped and TimeLimitExceeded is returned instead. Timeouts can be nested; the inner one can
+// only shorten the time the outer one has left, never extend it.
+//
+// The time limit is checked before each function call and on every iteration of `WHILE`, all on
+// the same goroutine. (Running the code on a separate goroutine would let it be interrupted at any
+// point, but the abandoned goroutine would keep running in the background, and would race with
+// everything else on the interpreter's global state, such as variables.) As such, functions which
+// block (such as `PROMPT`, or a slow command run by the backtick function) aren't interrupted; the
+// time limit is only noticed once they finish.
+//
+// ## Examples
+//
+//	DUMP XTIMEOUT 100 + 1 2          #=> 3
+//	DUMP XTIMEOUT 100 WHILE TRUE 1   #!! error: time limit exceeded
+//	DUMP XTIMEOUT 9223372036854775807 + 1 2  #=> 3
+//
+// ## Undefined Behaviour
+// Negative time limits yield an error:
+//
+//	DUMP XTIMEOUT ~1 1               #!! error: negative time limit
+func timeout(args []Value) (Value, error) {
+	milliseconds, err := executeToInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if milliseconds < 0 {
+		return nil, fmt.Errorf("negative time limit given to 'XTIMEOUT': %d", milliseconds)
+	}
+
+	// Only use our deadline if it's earlier than the current one (if any). If adding the time limit
+	// to the current time would go past the largest time there is, there's effectively no limit.
+	now := Now()
+	previous := deadline
+	ours := now.Add(millisecondsToDuration(milliseconds))
+	if ours.Before(now) {
+		return args[1].Execute()
+	}
+
+	if previous.IsZero() || ours.Before(previous) {
+		deadline = ours
+	}
+	defer func() { deadline = previous }()
+
+	return args[1].Execute()
+}
+
+// retry executes its first argument, returning the result if it succeeds. If it returns an error,
+// then it's retried up to the second argument's number of times, sleeping for the third argument's
+// number of milliseconds before each retry. If all of the attempts fail, the last error is returned.
+//
+// The second argument is the number of _retries_, so the first argument is executed at most one
+// more time than it. (So, `XRETRY body 0 0` is the same as just `body`.) The second and third
+// arguments are executed once, before the first attempt.
+//
+// If the time limit of an `XTIMEOUT` that `XRETRY` is running within passes (including while it's
+// sleeping), then no more retries are attempted, and TimeLimitExceeded is returned. (Time limits of
+// `XTIMEOUT`s within the first argument are retried like any other error.) Likewise, `QUIT` is
+// never retried (when QuitMode is ReturnError).
+//
+// ## Examples
+//
+//	; = n 0 : DUMP XRETRY (; = n + n 1 IF (< n 3) (+ TRUE 1) n) 5 10  #=> 3
+//	; = n 0 : DUMP XRETRY (; = n + n 1 IF (< n 3) (+ TRUE 1) n) 1 10  #!! error: invalid type
+//
+// ## Undefined Behaviour
+// Negative retry counts and delays yield an error:
+//
+//	DUMP XRETRY 1 ~1 0                                                #!! error: negative retries
+//	DUMP XRETRY 1 0 ~1                                                #!! error: negative delay
+func retry(args []Value) (Value, error) {
+	retries, err := executeToInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if retries < 0 {
+		return nil, fmt.Errorf("negative retries given to 'XRETRY': %d", retries)
+	}
+
+	delay, err := executeToInt(args[2])
+	if err != nil {
+		return nil, err
+	}
+	if delay < 0 {
+		return nil, fmt.Errorf("negative delay given to 'XRETRY': %d", delay)
+	}
+
+	for attempt := 0; ; attempt++ {
+		result, err := args[0].Execute()
+		if err == nil || attempt == retries || !catchable(err) {
+			return result, err
+		}
+
+		if err := sleep(millisecondsToDuration(delay)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// intervalArgument is a helper function for throttle and debounce. It executes interval and converts
+// it to a time.Duration of that many milliseconds, returning an error if it's negative. The
+// functionName argument is just used for error messages.
+func intervalArgument(interval Value, functionName string) (time.Duration, error) {
+	milliseconds, err := executeToInt(interval)
+	if err != nil {
+		return 0, err
+	}
+
+	if milliseconds < 0 {
+		return 0, fmt.Errorf("negative interval given to '%s': %d", functionName, milliseconds)
+	}
+
+	return millisecondsToDuration(milliseconds), nil
+}
+
+// throttle converts its first argument to an integer, and then executes and returns its second
+// argument, unless it was already executed less than that many milliseconds ago (in which case
+// Null is returned, and it's not executed). So, the second argument is executed at most once per
+// interval, no matter how often `XTHROTTLE` is called.
+//
+// Each `XTHROTTLE` in the source code keeps track of when its own body was last executed. If the
+// body returns an error, it still counts as having been executed.
+//
+// ## Examples
+//
+//	; = i 0 : WHILE (> 10 = i + i 1) (XTHROTTLE 1000 OUTPUT i)   #=> 1␤  (the rest are skipped)
+//
+// ## Undefined Behaviour
+// Negative intervals yield an error:
+//
+//	XTHROTTLE ~1 1                                               #!! error: negative interval
+func throttle(fnCall *FnCall) (Value, error) {
+	interval, err := intervalArgument(fnCall.arguments[0], "XTHROTTLE")
+	if err != nil {
+		return nil, err
+	}
+
+	now := Now()
+
+	timestampMutex.Lock()
+	last := fnCall.timestamp
+	skip := !last.IsZero() && now.Sub(last) < interval
+	if !skip {
+		fnCall.timestamp = now
+	}
+	timestampMutex.Unlock()
+
+	if skip {
+		return Null{}, nil
+	}
+
+	return fnCall.arguments[1].Execute()
+}
+
+// debounce converts its first argument to an integer, and then executes and returns its second
+// argument, unless the `XDEBOUNCE` was last _called_ less than that many milliseconds ago (in which
+// case Null is returned, and it's not executed). So, the second argument is only executed on the
+// first call after a "quiet period" of at least the interval.
+//
+// The difference from `XTHROTTLE` is that calls which are skipped still restart the quiet period:
+// Calling `XDEBOUNCE 100 ...` every 50 milliseconds executes its body only on the very first call,
+// whereas `XTHROTTLE 100 ...` would execute it on every other call.
+//
+// Each `XDEBOUNCE` in the source code keeps track of when it was last called.
+//
+// ## Examples
+//
+//	; = i 0 : WHILE (> 10 = i + i 1) (XDEBOUNCE 1000 OUTPUT i)   #=> 1␤  (the rest are skipped)
+//
+// ## Undefined Behaviour
+// Negative intervals yield an error:
+//
+//	XDEBOUNCE ~1 1                                               #!! error: negative interval
+func debounce(fnCall *FnCall) (Value, error) {
+	interval, err := intervalArgument(fnCall.arguments[0], "XDEBOUNCE")
+	if err != nil {
+		return nil, err
+	}
+
+	now := Now()
+
+	timestampMutex.Lock()
+	last := fnCall.timestamp
+	fnCall.timestamp = now
+	timestampMutex.Unlock()
+
+	if !last.IsZero() && now.Sub(last) < interval {
+		return Null{}, nil
+	}
+
+	return fnCall.arguments[1].Execute()
+}
+
+// timeExecution executes its argument, discarding the result, and returns how many milliseconds it
+// took (rounded down), according to Now. This lets Knight programs benchmark themselves. If the
+// argument returns an error, it's returned instead. When Deterministic is enabled, `0` is always
+// returned.
+//
+// ## Examples
+//
+//	DUMP XTIME + 1 2                                #=> 0
+//	; = f BLOCK XFIB 90 : OUTPUT XTIME CALL f       #=> 0  (or however long it took)
+//	DUMP XTIME XTIMEOUT 50 WHILE TRUE 1             #!! error: time limit exceeded
+func timeExecution(args []Value) (Value, error) {
+	start := Now()
+
+	if _, err := args[0].Execute(); err != nil {
+		return nil, err
+	}
+
+	if Deterministic {
+		return Integer(0), nil
+	}
+
+	return Integer(Now().Sub(start).Milliseconds()), nil
+}
+
+// doWhile is like `WHILE`, except that the second argument is evaluated _before_ the first is
+// checked each time, so it's always evaluated at least once. Like `WHILE`, it returns Null.
+//
+// ## Examples
+//
+//	; = i 0 : XDOWHILE (> 3 i) (OUTPUT = i + i 1)   #=> 1␤2␤3␤
+//	DUMP XDOWHILE FALSE (OUTPUT "hi")               #=> hi␤null  (the body is still run once)
+//	: XDOWHILE FALSE BLOCK 34                       # (works, `BLOCK` is allowed as the body)
+//
+// ## Undefined Behaviour
+// Conditions which can't be converted to booleans yield an error:
+//
+//	XDOWHILE (BLOCK foo) 1                          #!! error: cant convert to a boolean
+func doWhile(args []Value) (Value, error) {
+	for {
+		// See `while` for why this is needed.
+		if err := checkDeadline(); err != nil {
+			return nil, err
+		}
+
+		if _, err := args[1].Execute(); err != nil {
+			return nil, err
+		}
+
+		condition, err := executeToBool(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		if !condition {
+			return Null{}, nil
+		}
+	}
+}
+
+// forEach converts its first argument to a list, and then executes the third argument once for
+// each element, after assigning the element to the variable given as the second argument. Like
+// `WHILE`, it returns Null. Like `=`, the second argument must be a Variable, or an error is
+// returned.
+//
+// The list is converted before the loop starts, so assigning to the variable (or to whatever the
+// list came from) within the body doesn't change which elements are iterated over. After the loop,
+// the variable is left as the last element; if the list is empty, the variable isn't touched.
+//
+// ## Examples
+//
+//	XFOREACH +@123 n (OUTPUT * n n)                 #=> 1␤4␤9␤
+//	; = s 0 ; XFOREACH "abc" c (= s + s 1) : DUMP s #=> 3
+//	; XFOREACH +@12 x NULL : DUMP x                 #=> 2
+//	; XFOREACH @ x NULL : DUMP x                    #!! error: undefined variable
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XFOREACH` yield errors:
+//
+//	XFOREACH +@12 3 NULL                            #!! error: can only assign variables
+//	XFOREACH (BLOCK a) x NULL                       #!! error: cant convert to a list
+func forEach(args []Value) (Value, error) {
+	list, err := executeToSlice(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	variable, ok := args[1].(*Variable)
+	if !ok {
+		return nil, fmt.Errorf("invalid type given to 'XFOREACH': %T", args[1])
+	}
+
+	for _, element := range list {
+		// See `while` for why this is needed.
+		if err := checkDeadline(); err != nil {
+			return nil, err
+		}
+
+		variable.Assign(element)
+
+		if _, err := args[2].Execute(); err != nil {
+			return nil, err
+		}
+	}
+
+	return Null{}, nil
+}
+
+// abort converts its argument to a string, and then returns an *AbortError with that message.
+//
+// This is how Knight programs can signal errors: Unlike `QUIT`, which exits the entire process
+// immediately, `XABORT` makes the Go function executing the program (eg `Evaluate`) return an
+// error, just like a runtime error such as dividing by zero would. So, programs embedding Knight
+// can handle the error however they like. (When run from the command line, the message is printed
+// and the exit status is nonzero.)
+//
+// ## Examples
+//
+//	XABORT "oops"                    #!! error: oops
+//	; OUTPUT 1 ; XABORT 2 OUTPUT 3   #=> 1␤  (and then the error `2`)
+//
+// ## Undefined Behaviour
+// Types which can't be converted to strings yield an error:
+//
+//	XABORT BLOCK foo                 #!! error: cant convert to a string
+func abort(args []Value) (Value, error) {
+	message, err := executeToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, &AbortError{Message: message}
+}
+
+// try executes its first argument, and returns its result if it succeeds. If it returns an error
+// instead, the error's message is assigned to the variable `_1`, and then the second argument is
+// executed and its result is returned.
+//
+// All errors that happen while the first argument is executing are caught, including `XABORT`s,
+// runtime errors (such as dividing by zero or undefined variables), and exceeding `MaxCallDepth`.
+// However, `QUIT` still stops the program (even when QuitMode is ReturnError), and the time limit
+// of an `XTIMEOUT` that `XTRY` is running within isn't caught, as otherwise code could ignore it.
+// (Time limits of `XTIMEOUT`s within the first argument are caught, though.)
+//
+// Like `IF`'s branches, both arguments are executed directly; to use blocks stored in variables,
+// use `CALL` (eg `XTRY (CALL body) (CALL handler)`).
+//
+// ## Examples
+//
+//	DUMP XTRY (+ 1 2) (OUTPUT "unused")                            #=> 3
+//	DUMP XTRY (XABORT "oops") (+ "caught: " _1)                    #=> "caught: oops"
+//	DUMP XTRY (/ 1 0) 0                                            #=> 0
+//	DUMP XTRY (XTIMEOUT 10 WHILE TRUE 1) "caught"                  #=> "caught"
+//	XTIMEOUT 10 XTRY (WHILE TRUE 1) 0                              #!! error: time limit exceeded
+//	XTRY (; OUTPUT 1 ; XABORT 2 OUTPUT 3) (OUTPUT + "error: " _1)  #=> 1␤error: 2␤
+//	XTRY (QUIT 1) 0                                                # (exits with status 1)
+func try(args []Value) (Value, error) {
+	result, err := args[0].Execute()
+	if err == nil || !catchable(err) {
+		return result, err
+	}
+
+	// Don't include the line number in the message if positions are being recorded.
+	var runtimeError *RuntimeError
+	if errors.As(err, &runtimeError) {
+		err = runtimeError.Err
+	}
+
+	bindArguments(String(err.Error()))
+	return args[1].Execute()
+}
+
+// repeat converts its first argument to an integer, and then executes the second argument that
+// many times, returning the result of the last execution (or Null, if it's not executed at all).
+// Like `WHILE`'s body, the second argument is executed directly; to repeat a block stored in a
+// variable, use `CALL` (eg `XREPEAT 3 CALL b`).
+//
+// ## Examples
+//
+//	XREPEAT 3 OUTPUT "hi"                 #=> hi␤hi␤hi␤
+//	; = i 0 : DUMP XREPEAT 4 (= i + i 1)  #=> 4
+//	DUMP XREPEAT 0 OUTPUT "hi"            #=> null
+//
+// ## Undefined Behaviour
+// Negative counts yield an error:
+//
+//	XREPEAT ~1 OUTPUT "hi"                #!! error: negative count
+func repeat(args []Value) (Value, error) {
+	count, err := executeToInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if count < 0 {
+		return nil, fmt.Errorf("negative count given to 'XREPEAT': %d", count)
+	}
+
+	var result Value = Null{}
+	for i := 0; i < count; i++ {
+		// See `while` for why this is needed.
+		if err := checkDeadline(); err != nil {
+			return nil, err
+		}
+
+		if result, err = args[1].Execute(); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// assert executes both its arguments, and returns Null if they're equal (using the same semantics
+// as `?`). Otherwise, it returns an error which includes what `DUMP` would write for both of them.
+// This is an extension (not part of the Knight spec), intended for writing tests in Knight itself.
+// Like other errors, failed assertions can be caught via `XTRY`.
+//
+// ## Examples
+//
+//	DUMP XASSERT (+ 1 2) 3        #=> null
+//	XASSERT (+ "a" 1) "a1"        # (does nothing)
+//	XASSERT (+ 1 2) 4             #!! error: assertion failed: 3 != 4
+//	XASSERT "1" 1                 #!! error: assertion failed: "1" != 1  (there's no coercion)
+func assert(args []Value) (Value, error) {
+	actual, err := args[0].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := args[1].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		return nil, fmt.Errorf("assertion failed: %s != %s", dumpToString(actual),
+			dumpToString(expected))
+	}
+
+	return Null{}, nil
+}
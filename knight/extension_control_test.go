@@ -0,0 +1,249 @@
+package knight
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTryCatchesInnerTimeout(t *testing.T) {
+	result, err := Evaluate(`XTRY (XTIMEOUT 10 WHILE TRUE 1) "caught"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result != String("caught") {
+		t.Errorf("expected %q, got %#v", "caught", result)
+	}
+}
+
+func TestTryDoesntCatchEnclosingTimeout(t *testing.T) {
+	for _, program := range []string{
+		`XTIMEOUT 10 XTRY (WHILE TRUE 1) 0`,
+		`XTIMEOUT 10 XTRY (XTIMEOUT 1000 WHILE TRUE 1) 0`,
+		`XTIMEOUT 10 XRETRY (WHILE TRUE 1) 1000 0`,
+	} {
+		_, err := Evaluate(program)
+		if !errors.Is(err, TimeLimitExceeded) {
+			t.Errorf("%s: expected TimeLimitExceeded, got %v", program, err)
+		}
+	}
+}
+
+func TestRetryDelayRespectsTimeout(t *testing.T) {
+	defer useFakeClock()()
+	start := Now()
+
+	_, err := Evaluate("XTIMEOUT 50 XRETRY (XABORT 1) 3 1000")
+	if !errors.Is(err, TimeLimitExceeded) {
+		t.Fatalf("expected TimeLimitExceeded, got %v", err)
+	}
+
+	// The first delay is cut short by the time limit.
+	if elapsed := Now().Sub(start); elapsed != 50*time.Millisecond {
+		t.Errorf("expected 50ms to pass, but %v did", elapsed)
+	}
+}
+
+func TestThrottleAndDebounce(t *testing.T) {
+	defer useFakeClock()()
+
+	// They're each executed six times, 50ms apart: `XTHROTTLE` executes its body every 100ms,
+	// whereas `XDEBOUNCE` only executes it the first time, as it's always called again within 100ms.
+	for _, test := range []struct {
+		program  string
+		expected int
+	}{
+		{"XTHROTTLE 100 (= n + n 1)", 3},
+		{"XDEBOUNCE 100 (= n + n 1)", 1},
+	} {
+		program, err := Parse(test.program)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		SetVariable("n", Integer(0))
+		for i := 0; i < 6; i++ {
+			if _, err := program.Execute(); err != nil {
+				t.Fatalf("%s: unexpected error: %v", test.program, err)
+			}
+
+			Sleep(50 * time.Millisecond)
+		}
+
+		if n, _ := LookupVariable("n"); n != Integer(test.expected) {
+			t.Errorf("%s: expected the body to be executed %d times, got %#v", test.program, test.expected, n)
+		}
+	}
+}
+
+// This is mainly useful when run with `go test -race`.
+func TestThrottleConcurrently(t *testing.T) {
+	program, err := Parse("XTHROTTLE 1000000 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var executed atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			result, err := program.Execute()
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if result == Integer(1) {
+				executed.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if executed.Load() != 1 {
+		t.Errorf("expected the body to be executed once, but it was executed %d times", executed.Load())
+	}
+}
+
+func TestMillisecondsToDuration(t *testing.T) {
+	for _, test := range []struct {
+		milliseconds int
+		expected     time.Duration
+	}{
+		{0, 0},
+		{1500, 1500 * time.Millisecond},
+		{int(math.MaxInt64 / time.Millisecond), math.MaxInt64 / time.Millisecond * time.Millisecond},
+		{int(math.MaxInt64/time.Millisecond) + 1, math.MaxInt64},
+		{math.MaxInt, math.MaxInt64},
+	} {
+		if result := millisecondsToDuration(test.milliseconds); result != test.expected {
+			t.Errorf("%d: expected %v, got %v", test.milliseconds, test.expected, result)
+		}
+	}
+}
+
+func TestHugeTimeout(t *testing.T) {
+	result, err := Evaluate("XTIMEOUT 9223372036854775807 + 1 2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result != Integer(3) {
+		t.Errorf("expected 3, got %#v", result)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	const body = "(; = n + n 1 IF (< n 3) (XABORT 1) n)"
+
+	result, err := Evaluate("; = n 0 : XRETRY " + body + " 5 0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// It succeeds on the third try.
+	if result != Integer(3) {
+		t.Errorf("expected 3, got %#v", result)
+	}
+
+	// With only one retry, it fails on both tries.
+	var abortError *AbortError
+	if _, err := Evaluate("; = n 0 : XRETRY " + body + " 1 0"); !errors.As(err, &abortError) {
+		t.Errorf("expected an AbortError, got %v", err)
+	}
+
+	if n, _ := LookupVariable("n"); n != Integer(2) {
+		t.Errorf("expected two tries, got %#v", n)
+	}
+}
+
+func TestRetryHugeDelay(t *testing.T) {
+	defer useFakeClock()()
+	start := Now()
+
+	if _, err := Evaluate("XTRY (XRETRY (XABORT 1) 1 9300000000000) 0"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The delay is too large to fit in a Duration, so it's waited for as long as possible.
+	if elapsed := Now().Sub(start); elapsed != math.MaxInt64 {
+		t.Errorf("expected the longest possible duration to pass, but %v did", elapsed)
+	}
+}
+
+func TestThrottleHugeInterval(t *testing.T) {
+	defer func(stdout io.Writer) { Stdout = stdout }(Stdout)
+
+	var buffer bytes.Buffer
+	Stdout = &buffer
+
+	if _, err := Evaluate("; = i 0 : WHILE (> 3 = i + i 1) (XTHROTTLE 9300000000000 OUTPUT i)"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if buffer.String() != "1\n" {
+		t.Errorf("expected only the first iteration to output, got %q", buffer.String())
+	}
+}
+
+func TestLet(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		// After returning normally.
+		{"; = let_a 1 ; = let_inner XLET let_a 2 let_a : + let_inner let_a", Integer(3)},
+		{"; = let_a 1 ; = let_b BLOCK * let_a 10 : XLET let_a 3 CALL let_b", Integer(30)},
+
+		// After an error.
+		{`; = let_a 1 ; XTRY (XLET let_a 2 XABORT "oops") 0 : let_a`, Integer(1)},
+
+		{"XLET 12 34 56", nil},
+	})
+}
+
+func TestLetUnassigns(t *testing.T) {
+	for _, program := range []string{
+		"XLET let_unassigned 1 let_unassigned",
+		"XLET let_unassigned 1 XABORT let_unassigned",
+	} {
+		_, _ = Evaluate(program)
+
+		if value, ok := LookupVariable("let_unassigned"); ok {
+			t.Errorf("%s: expected the variable to be unassigned, got %#v", program, value)
+		}
+	}
+}
+
+func TestDoWhile(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		// The body runs once, even though the condition is false to begin with.
+		{"; = do_n 0 ; XDOWHILE FALSE (= do_n + do_n 1) : do_n", Integer(1)},
+		{"; = do_n 10 ; XDOWHILE (< do_n 5) (= do_n + do_n 1) : do_n", Integer(11)},
+		{"; = do_n 0 ; XDOWHILE (< do_n 5) (= do_n + do_n 1) : do_n", Integer(5)},
+		{"XDOWHILE FALSE 1", Null{}},
+		{"XDOWHILE (BLOCK foo) 1", nil},
+	})
+}
+
+func TestForEach(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{"; = each_sum 0 ; XFOREACH +@123 each_x (= each_sum + each_sum each_x) : each_sum", Integer(6)},
+		{`; = each_s "" ; XFOREACH "abc" each_c (= each_s + each_c each_s) : each_s`, String("cba")},
+
+		// The loop variable is left as the last element.
+		{"; XFOREACH +@123 each_last NULL : each_last", Integer(3)},
+
+		// Empty lists don't touch the loop variable.
+		{"; = each_untouched 9 ; XFOREACH @ each_untouched NULL : each_untouched", Integer(9)},
+		{"; = each_count 0 ; XFOREACH @ each_y (= each_count + each_count 1) : each_count", Integer(0)},
+
+		{"XFOREACH +@12 3 NULL", nil},
+		{"XFOREACH (BLOCK a) each_x NULL", nil},
+	})
+}
@@ -0,0 +1,374 @@
+package knight
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Register the structured data extension functions. (See `init` in `function.go` for more details.)
+func init() {
+	ExtensionFunctions["XINI"] = &Function{name: "XINI", arity: 1, fn: parseIni}
+	ExtensionFunctions["XGETIN"] = &Function{name: "XGETIN", arity: 2, fn: getIn}
+	ExtensionFunctions["XSETIN"] = &Function{name: "XSETIN", arity: 3, fn: setIn}
+	ExtensionFunctions["XLOOKUP"] = &Function{name: "XLOOKUP", arity: 2, fn: lookup}
+	ExtensionFunctions["XASSOC"] = &Function{name: "XASSOC", arity: 3, fn: assoc}
+	ExtensionFunctions["XTABLE"] = &Function{name: "XTABLE", arity: 1, fn: table}
+}
+
+// parseIni converts its argument to a string, and then parses it as an INI file. It returns a list
+// of sections, each of which is a two-element list of the section's name and a list of its
+// `[key, value]` pairs, in the order they appeared.
+//
+// Each line (with leading and trailing whitespace removed) is handled as follows:
+//
+//   - Blank lines, and lines starting with `;` or `#` (comments), are ignored.
+//   - `[name]` starts a new section called `name`.
+//   - `key=value` adds a pair to the current section. Only the first `=` is special, and whitespace
+//     around both the key and value is removed.
+//
+// Pairs which come before the first section are placed in a section whose name is the empty string.
+// (That section is only included if there's at least one such pair.) Duplicate sections and keys
+// are kept as-is.
+//
+// ## Examples
+//
+//	DUMP XINI "a=1␤[s]␤; hi␤b = 2"  #=> [["", [["a", "1"]]], ["s", [["b", "2"]]]]
+//	DUMP XINI "[s]␤c=d=e"           #=> [["s", [["c", "d=e"]]]]
+//	DUMP XINI ""                    #=> []
+//
+// ## Undefined Behaviour
+// Lines which aren't blank, comments, sections, or pairs yield an error:
+//
+//	DUMP XINI "[s]␤oops"            #!! error: invalid line
+func parseIni(args []Value) (Value, error) {
+	source, err := executeToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	sections := List{}
+	name := ""     // the current section's name
+	var pairs List // the current section's pairs
+
+	for lineno, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#"):
+			continue
+
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			// Only add the section without a name if something was actually in it.
+			if name != "" || len(pairs) != 0 {
+				sections = append(sections, List{String(name), pairs})
+			}
+
+			name = line[1 : len(line)-1]
+			pairs = List{}
+
+		default:
+			key, value, found := strings.Cut(line, "=")
+			if !found {
+				return nil, fmt.Errorf("invalid line %d given to 'XINI': %q", lineno+1, line)
+			}
+
+			pairs = append(pairs, List{String(strings.TrimSpace(key)), String(strings.TrimSpace(value))})
+		}
+	}
+
+	if name != "" || len(pairs) != 0 {
+		sections = append(sections, List{String(name), pairs})
+	}
+
+	return sections, nil
+}
+
+// getIn returns the element of a nested list addressed by a path, which is a list of indices. Each
+// index in the path selects an element of the list selected by the previous one, starting with the
+// first argument. `Null` is returned if any index is out of bounds.
+//
+// ## Examples
+//
+//	; = data +@ ,+@ ,,"hi"             # (`data` is `[[["hi"]]]`)
+//	DUMP XGETIN data ,0                #=> [["hi"]]
+//	DUMP XGETIN data *,0 3             #=> "hi"
+//	DUMP XGETIN data @                 #=> [[["hi"]]]  (an empty path selects the list itself)
+//	DUMP XGETIN data +,0 ,1            #=> null        (index out of bounds)
+//	DUMP XGETIN data ,~1               #=> null        (negative indices are out of bounds too)
+//
+// ## Undefined Behaviour
+// Indexing into something that's not a list yields an error:
+//
+//	DUMP XGETIN data *,0 4             #!! error: "hi" isn't a list
+func getIn(args []Value) (Value, error) {
+	current, err := args[0].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := executeToSlice(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, element := range path {
+		index, err := element.ToInt()
+		if err != nil {
+			return nil, err
+		}
+
+		list, ok := current.(List)
+		if !ok {
+			return nil, fmt.Errorf("invalid type indexed by 'XGETIN': %T", current)
+		}
+
+		if index < 0 || len(list) <= index {
+			return Null{}, nil
+		}
+
+		current = list[index]
+	}
+
+	return current, nil
+}
+
+// setIn returns a copy of a nested list where the element addressed by a path (in the same format
+// as `XGETIN`'s) is replaced by the third argument. The original list is left unchanged: Each list
+// along the path is copied, and all other elements are shared with the original. An error is
+// returned if any index in the path is out of bounds.
+//
+// ## Examples
+//
+//	; = data +@ ,+@ ,,"hi"             # (`data` is `[[["hi"]]]`)
+//	DUMP XSETIN data *,0 3 "bye"       #=> [[["bye"]]]
+//	DUMP XSETIN data +,0 ,0 TRUE       #=> [[true]]
+//	DUMP XSETIN data @ 3               #=> 3           (an empty path replaces the list itself)
+//	DUMP data                          #=> [[["hi"]]]  (the original is unchanged)
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XSETIN` yield errors:
+//
+//	DUMP XSETIN data ,1 3              #!! error: index out of bounds
+//	DUMP XSETIN data *,0 4 3           #!! error: "hi" isn't a list
+func setIn(args []Value) (Value, error) {
+	root, err := args[0].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := executeToSlice(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	replacement, err := args[2].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	return replaceIn(root, path, replacement)
+}
+
+// replaceIn is the recursive helper for setIn: It returns a copy of container with the element at
+// path replaced by replacement.
+func replaceIn(container Value, path List, replacement Value) (Value, error) {
+	if len(path) == 0 {
+		return replacement, nil
+	}
+
+	index, err := path[0].ToInt()
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := container.(List)
+	if !ok {
+		return nil, fmt.Errorf("invalid type indexed by 'XSETIN': %T", container)
+	}
+
+	if index < 0 || len(list) <= index {
+		return nil, fmt.Errorf("index out of bounds for 'XSETIN': %d (length %d)", index, len(list))
+	}
+
+	element, err := replaceIn(list[index], path[1:], replacement)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the list, so that the original isn't modified.
+	updated := make(List, len(list))
+	copy(updated, list)
+	updated[index] = element
+
+	return updated, nil
+}
+
+// findPair is a helper function for lookup and assoc. It returns the index of the first pair in the
+// association list `pairs` whose key is equal to key (using the same semantics as `?`), or `-1` if
+// there is none. An error is returned if an element of `pairs` isn't a two-element list. The
+// functionName argument is just used for error messages.
+func findPair(pairs List, key Value, functionName string) (int, error) {
+	for i, element := range pairs {
+		pair, ok := element.(List)
+		if !ok || len(pair) != 2 {
+			return 0, fmt.Errorf("invalid pair given to '%s': %s", functionName, dumpToString(element))
+		}
+
+		if reflect.DeepEqual(pair[0], key) {
+			return i, nil
+		}
+	}
+
+	return -1, nil
+}
+
+// lookup treats its first argument as an association list (ie a list of `[key, value]` pairs) and
+// returns the value of the first pair whose key is equal to the second argument, or Null if there
+// isn't one. Keys are compared the same way `?` compares its arguments (so there's no coercion).
+//
+// Since Knight doesn't have maps, this is a linear scan, so it takes time proportional to the
+// length of the list.
+//
+// ## Examples
+//
+//	; = pairs +,+,"a" 1 ,+,"b" 2         # (`pairs` is `[["a", 1], ["b", 2]]`)
+//	DUMP XLOOKUP pairs "b"               #=> 2
+//	DUMP XLOOKUP pairs "c"               #=> null
+//	DUMP XLOOKUP (+ ,+,"a" 1 pairs) "a"  #=> 1  (only the first match is used)
+//
+// ## Undefined Behaviour
+// Elements which aren't two-element lists yield an error:
+//
+//	DUMP XLOOKUP ,1 1                    #!! error: invalid pair
+func lookup(args []Value) (Value, error) {
+	pairs, err := executeToSlice(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := args[1].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := findPair(pairs, key, "XLOOKUP")
+	if err != nil {
+		return nil, err
+	}
+
+	if index < 0 {
+		return Null{}, nil
+	}
+
+	return pairs[index].(List)[1], nil
+}
+
+// assoc treats its first argument as an association list (see lookup), and returns a copy of it
+// where the value of the first pair whose key is equal to the second argument is replaced by the
+// third argument. If there's no such pair, a new one is added to the end instead. Any later pairs
+// with the same key are left as-is (and are still shadowed by the first one).
+//
+// ## Examples
+//
+//	; = pairs +,+,"a" 1 ,+,"b" 2         # (`pairs` is `[["a", 1], ["b", 2]]`)
+//	DUMP XASSOC pairs "a" 3              #=> [["a", 3], ["b", 2]]
+//	DUMP XASSOC pairs "c" 3              #=> [["a", 1], ["b", 2], ["c", 3]]
+//	DUMP XASSOC @ "a" 1                  #=> [["a", 1]]
+//
+// ## Undefined Behaviour
+// Elements which aren't two-element lists yield an error:
+//
+//	DUMP XASSOC ,1 1 2                   #!! error: invalid pair
+func assoc(args []Value) (Value, error) {
+	pairs, err := executeToSlice(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := args[1].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := args[2].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := findPair(pairs, key, "XASSOC")
+	if err != nil {
+		return nil, err
+	}
+
+	if index < 0 {
+		return slices.Concat(pairs, List{List{key, value}}), nil
+	}
+
+	// Copy the list, so that the original isn't modified.
+	updated := slices.Clone(pairs)
+	updated[index] = List{key, value}
+	return updated, nil
+}
+
+// table treats its argument as an association list (see lookup), and returns a string with a
+// `key: value` line for each pair, in order. Keys are padded with spaces to the length of the
+// longest key (measured in runes), so that all the colons line up directly after it. Keys and values
+// are converted to strings the same way `OUTPUT` does. Lines are separated by `\n`, and there's no
+// trailing newline.
+//
+// ## Examples
+//
+//	OUTPUT XTABLE +,+,"name" ,"knight" ,+,"version" 3  #=> name   : knight␤version: 3␤
+//	DUMP XTABLE @                                       #=> ""
+//
+// ## Undefined Behaviour
+// Elements which aren't two-element lists, or keys and values which can't be converted to strings,
+// yield an error:
+//
+//	DUMP XTABLE ,1                                      #!! error: invalid pair
+func table(args []Value) (Value, error) {
+	pairs, err := executeToSlice(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(pairs))
+	values := make([]string, len(pairs))
+	width := 0
+
+	for i, element := range pairs {
+		pair, ok := element.(List)
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("invalid pair given to 'XTABLE': %s", dumpToString(element))
+		}
+
+		if keys[i], err = pair[0].ToString(); err != nil {
+			return nil, err
+		}
+
+		if values[i], err = pair[1].ToString(); err != nil {
+			return nil, err
+		}
+
+		if keyWidth := utf8.RuneCountInString(keys[i]); width < keyWidth {
+			width = keyWidth
+		}
+	}
+
+	var builder strings.Builder
+	for i := range pairs {
+		if i != 0 {
+			builder.WriteString("\n")
+		}
+
+		builder.WriteString(keys[i])
+		builder.WriteString(strings.Repeat(" ", width-utf8.RuneCountInString(keys[i])))
+		builder.WriteString(": ")
+		builder.WriteString(values[i])
+	}
+
+	return String(builder.String()), nil
+}
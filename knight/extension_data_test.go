@@ -0,0 +1,203 @@
+package knight
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	for _, test := range []struct {
+		pairs    List
+		expected String
+	}{
+		{List{}, ""},
+		{List{List{String("a"), Integer(1)}}, "a: 1"},
+		{List{
+			List{String("name"), String("knight")},
+			List{String("version"), Integer(3)},
+			List{String("é"), Null{}},
+		}, "name   : knight\nversion: 3\né      : "},
+	} {
+		result, err := table([]Value{test.pairs})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+
+		if result != test.expected {
+			t.Errorf("expected %q, got %#v", test.expected, result)
+		}
+	}
+}
+
+func TestParseIni(t *testing.T) {
+	const source = `
+top = level
+# a comment
+
+[first]
+a=1
+; another comment
+b = two words = three
+
+[second]
+   c   =   3
+[empty]
+`
+
+	result, err := parseIni([]Value{String(source)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := List{
+		List{String(""), List{List{String("top"), String("level")}}},
+		List{String("first"), List{
+			List{String("a"), String("1")},
+			List{String("b"), String("two words = three")},
+		}},
+		List{String("second"), List{List{String("c"), String("3")}}},
+		List{String("empty"), List{}},
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %s, got %s", dumpToString(expected), dumpToString(result))
+	}
+}
+
+func TestParseIniErrors(t *testing.T) {
+	for _, source := range []string{"[s]\noops", "a=1\n[unterminated"} {
+		_, err := parseIni([]Value{String(source)})
+		if err == nil || !strings.Contains(err.Error(), "invalid line 2") {
+			t.Errorf("%q: expected an invalid line error, got %v", source, err)
+		}
+	}
+}
+
+func TestGetIn(t *testing.T) {
+	data := List{Integer(0), List{Integer(1), List{Integer(2), List{String("deep")}}}}
+
+	for _, test := range []struct {
+		path     List
+		expected Value
+	}{
+		{List{}, data},
+		{List{Integer(0)}, Integer(0)},
+		{List{Integer(1), Integer(1), Integer(1), Integer(0)}, String("deep")},
+		{List{Integer(1), Integer(1), Integer(0)}, Integer(2)},
+		{List{Integer(2)}, Null{}},
+		{List{Integer(1), Integer(5), Integer(0)}, Null{}},
+		{List{Integer(-1)}, Null{}},
+	} {
+		result, err := getIn([]Value{data, test.path})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", dumpToString(test.path), err)
+			continue
+		}
+
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("%s: expected %s, got %s", dumpToString(test.path), dumpToString(test.expected),
+				dumpToString(result))
+		}
+	}
+
+	// Indexing into something that isn't a list is an error, even if the index would be in bounds.
+	for _, path := range []List{
+		{Integer(0), Integer(0)},
+		{Integer(1), Integer(1), Integer(1), Integer(0), Integer(0)},
+	} {
+		if _, err := getIn([]Value{data, path}); err == nil {
+			t.Errorf("%s: expected an error", dumpToString(path))
+		}
+	}
+}
+
+func TestSetIn(t *testing.T) {
+	inner := List{Integer(2), Integer(3)}
+	data := List{Integer(1), inner}
+
+	result, err := setIn([]Value{data, List{Integer(1), Integer(0)}, String("new")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := List{Integer(1), List{String("new"), Integer(3)}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %s, got %s", dumpToString(expected), dumpToString(result))
+	}
+
+	// Neither the original list nor the lists inside it are modified.
+	if !reflect.DeepEqual(data, List{Integer(1), List{Integer(2), Integer(3)}}) {
+		t.Errorf("the original list was modified: %s", dumpToString(data))
+	}
+
+	if !reflect.DeepEqual(inner, List{Integer(2), Integer(3)}) {
+		t.Errorf("the original inner list was modified: %s", dumpToString(inner))
+	}
+}
+
+func TestSetInErrors(t *testing.T) {
+	data := List{Integer(1), List{Integer(2)}}
+
+	for _, path := range []List{
+		{Integer(2)},
+		{Integer(-1)},
+		{Integer(1), Integer(1)},
+		{Integer(0), Integer(0)},
+	} {
+		if _, err := setIn([]Value{data, path, Null{}}); err == nil {
+			t.Errorf("%s: expected an error", dumpToString(path))
+		}
+	}
+}
+
+func TestLookupAndAssoc(t *testing.T) {
+	pair := func(key string, value int) List { return List{String(key), Integer(value)} }
+	pairs := List{pair("a", 1), pair("b", 2), pair("a", 3)}
+
+	for _, test := range []struct {
+		name     string
+		fn       func([]Value) (Value, error)
+		args     []Value
+		expected Value
+	}{
+		{"hit", lookup, []Value{pairs, String("b")}, Integer(2)},
+		{"miss", lookup, []Value{pairs, String("c")}, Null{}},
+		{"no coercion", lookup, []Value{List{List{Integer(1), Integer(2)}}, String("1")}, Null{}},
+		{"duplicate keys", lookup, []Value{pairs, String("a")}, Integer(1)},
+		{"empty", lookup, []Value{List{}, String("a")}, Null{}},
+
+		{"update", assoc, []Value{pairs, String("b"), Integer(4)},
+			List{pair("a", 1), pair("b", 4), pair("a", 3)}},
+		{"update duplicate keys", assoc, []Value{pairs, String("a"), Integer(4)},
+			List{pair("a", 4), pair("b", 2), pair("a", 3)}},
+		{"insert", assoc, []Value{pairs, String("c"), Integer(4)},
+			List{pair("a", 1), pair("b", 2), pair("a", 3), pair("c", 4)}},
+		{"insert into empty", assoc, []Value{List{}, String("a"), Integer(1)}, List{pair("a", 1)}},
+	} {
+		result, err := test.fn(test.args)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("%s: expected %s, got %s", test.name, dumpToString(test.expected), dumpToString(result))
+		}
+	}
+
+	// Updates don't modify the original list.
+	if !reflect.DeepEqual(pairs, List{pair("a", 1), pair("b", 2), pair("a", 3)}) {
+		t.Errorf("the original list was modified: %s", dumpToString(pairs))
+	}
+}
+
+func TestLookupAndAssocErrors(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{"XLOOKUP ,1 1", nil},
+		{"XLOOKUP ,,1 1", nil},
+		{"XASSOC ,1 1 2", nil},
+		{"XASSOC ,+@123 1 2", nil},
+	})
+}
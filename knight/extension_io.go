@@ -0,0 +1,388 @@
+package knight
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Args are the command-line arguments given to the Knight program, which `XARGS` returns. It's empty
+// by default; the `knight` command sets it to the arguments after the program (see `main.go`).
+var Args []string
+
+// Register the input and output extension functions. (See `init` in `function.go` for more
+// details.)
+func init() {
+	ExtensionFunctions["XCAPTURE"] = &Function{name: "XCAPTURE", arity: 1, fn: capture}
+	ExtensionFunctions["XTEE"] = &Function{name: "XTEE", arity: 1, fn: tee}
+	ExtensionFunctions["XPROMPTINT"] = &Function{name: "XPROMPTINT", arity: 0, fn: promptInt}
+	ExtensionFunctions["XERROUT"] = &Function{name: "XERROUT", arity: 1, fn: errorOutput}
+	ExtensionFunctions["XFLUSH"] = &Function{name: "XFLUSH", arity: 0, fn: flush}
+	ExtensionFunctions["XEOF"] = &Function{name: "XEOF", arity: 0, fn: eof}
+	ExtensionFunctions["XGETCHAR"] = &Function{name: "XGETCHAR", arity: 0, fn: getChar}
+	ExtensionFunctions["XPUT"] = &Function{name: "XPUT", arity: 1, fn: put}
+	ExtensionFunctions["XARGS"] = &Function{name: "XARGS", arity: 0, fn: arguments}
+	ExtensionFunctions["XREADFILE"] = &Function{name: "XREADFILE", arity: 1, fn: readFile}
+	ExtensionFunctions["XWRITEFILE"] = &Function{name: "XWRITEFILE", arity: 2, fn: writeFile}
+	ExtensionFunctions["XAPPENDFILE"] = &Function{name: "XAPPENDFILE", arity: 2, fn: appendFile}
+}
+
+// capture executes its argument, and returns everything it wrote to Stdout (eg via `OUTPUT` or
+// `DUMP`) as a string, instead of writing it. Stdout is restored afterwards, even if an error
+// occurs. Captures can be nested, in which case the inner capture's output isn't visible to the
+// outer one (unless the inner one's result is written).
+//
+// ## Examples
+//
+//	DUMP XCAPTURE ; OUTPUT 1 DUMP 2                   #=> "1\n2"
+//	DUMP XCAPTURE ; OUTPUT 1 OUTPUT XCAPTURE OUTPUT 2 #=> "1\n2\n\n"
+//	; = b BLOCK OUTPUT "hi" : DUMP XCAPTURE CALL b    #=> "hi\n"
+func capture(args []Value) (Value, error) {
+	var builder strings.Builder
+
+	// Save the old Stdout so we can restore it; since each capture saves the previous one, nested
+	// captures restore them in the right order.
+	previous := Stdout
+	Stdout = &builder
+	defer func() { Stdout = previous }()
+
+	if _, err := args[0].Execute(); err != nil {
+		return nil, err
+	}
+
+	return String(builder.String()), nil
+}
+
+// tee is like capture, except that what's written to Stdout is also still written to the previous
+// Stdout, in addition to being returned.
+//
+// Output is written to both destinations as soon as it's produced, so nothing is buffered by `XTEE`
+// itself. However, while `XTEE` is running, Stdout can't be flushed, so `OUTPUT`'s flushing after a
+// trailing `\` (and `XFLUSH`) won't reach the original Stdout.
+//
+// ## Examples
+//
+//	DUMP XTEE OUTPUT 1                    #=> 1␤"1\n"
+//	DUMP XCAPTURE DUMP XTEE OUTPUT 1      #=> "1\n\"1\\n\""
+func tee(args []Value) (Value, error) {
+	var builder strings.Builder
+
+	// See `capture` for why this handles nested tees correctly.
+	previous := Stdout
+	Stdout = io.MultiWriter(previous, &builder)
+	defer func() { Stdout = previous }()
+
+	if _, err := args[0].Execute(); err != nil {
+		return nil, err
+	}
+
+	return String(builder.String()), nil
+}
+
+// promptInt reads lines from stdin (like `PROMPT`) until one of them is an integer, and returns that
+// integer. Null is returned if stdin is empty before an integer is read.
+//
+// Unlike converting strings to integers normally, this is strict: Other than leading and trailing
+// whitespace, the entire line must be a base-10 integer (with an optional leading `+` or `-`). For
+// each line that isn't, `please enter an integer` (followed by a newline) is written to Stderr
+// before the next line is read.
+//
+// ## Examples
+//
+//	DUMP XPROMPTINT <stdin="12">           #=> 12
+//	DUMP XPROMPTINT <stdin=" -3 ">         #=> -3
+//	DUMP XPROMPTINT <stdin="a\n4b\n5">     #=> 5     (and the message is written to stderr twice)
+//	DUMP XPROMPTINT <stdin="a">            #=> null  (and the message is written to stderr once)
+func promptInt(_ []Value) (Value, error) {
+	for {
+		line, err := prompt(nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// `prompt` returns Null at the end of stdin.
+		if _, ok := line.(Null); ok {
+			return line, nil
+		}
+
+		integer, err := strconv.Atoi(strings.TrimSpace(string(line.(String))))
+		if err == nil {
+			return Integer(integer), nil
+		}
+
+		fmt.Fprintln(Stderr, "please enter an integer")
+	}
+}
+
+// errorOutput is like `OUTPUT`, except it writes to Stderr instead of Stdout, so that diagnostics
+// can be kept separate from a program's actual output. Like `OUTPUT`, a trailing `\` suppresses
+// the newline, and Null is returned. (Unlike `OUTPUT`, it's never limited by OutputLinesPerSecond,
+// and isn't affected by `XCAPTURE` or `XTEE`.)
+//
+// ## Examples
+//
+//	XERROUT "oops"          #=> oops␤  (written to stderr)
+//	XERROUT "no newline\"   #=> no newline  (written to stderr)
+//
+// ## Undefined Behaviour
+// Types which can't be converted to strings yield an error:
+//
+//	XERROUT BLOCK foo       #!! error: cant convert to a string
+//
+// Any errors with writing to Stderr are silently ignored.
+func errorOutput(args []Value) (Value, error) {
+	message, err := executeToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	writeOutput(Stderr, message)
+	return Null{}, nil
+}
+
+// flush flushes Stdout, and returns Null.
+//
+// When Stdout is buffered (eg if a program embedding Knight sets it to a `*bufio.Writer`), what's
+// written by `OUTPUT` and `DUMP` isn't visible until the buffer is flushed, which is a problem for
+// interactive programs (such as ones that print a prompt and then wait for input via `PROMPT`).
+// `OUTPUT` automatically flushes when its argument ends in a `\`, but `XFLUSH` can be used to
+// flush at any time. When Stdout is unbuffered (such as the default, the standard output), output
+// is always visible immediately, and `XFLUSH` just asks the OS to sync it.
+//
+// Specifically, Stdout is flushed if it has a `Flush() error` method (returning any error), or
+// synced if it has a `Sync() error` method; otherwise, nothing is done.
+//
+// ## Examples
+//
+//	; DUMP 1 XFLUSH   #=> 1
+func flush(_ []Value) (Value, error) {
+	if err := flushWriter(Stdout); err != nil {
+		return nil, err
+	}
+
+	return Null{}, nil
+}
+
+// eof returns whether there's no input left to read from Stdin, without consuming any of it. When
+// it returns false, the next `PROMPT` will return a string; when it returns true, it'll return
+// Null. So, `WHILE ! XEOF (OUTPUT PROMPT)` echoes every line of stdin.
+//
+// Since Stdin is buffered, this only has to read from the standard input when the buffer is empty,
+// and it then reads as much as is available (which isn't lost, as `PROMPT` reads from the same
+// buffer). However, when the standard input is interactive (eg a terminal), checking whether
+// there's more input has to wait until the user types a line (or signals end-of-file).
+//
+// ## Examples
+//
+//	DUMP XEOF <stdin="">             #=> true
+//	DUMP XEOF <stdin="a">            #=> false
+//	DUMP ; PROMPT XEOF <stdin="a">   #=> true
+//	DUMP ; PROMPT XEOF <stdin="a\n"> #=> true
+//
+// ## Undefined Behaviour
+// Errors reading from Stdin (other than the end of the input) are returned.
+func eof(_ []Value) (Value, error) {
+	_, err := Stdin.Peek(1)
+	if err == io.EOF {
+		return Boolean(true), nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to 'XEOF': %v", err)
+	}
+
+	return Boolean(false), nil
+}
+
+// getChar reads a single rune from Stdin, and returns it as a string, or Null if Stdin is empty.
+// Line endings aren't treated specially, so a newline is returned as `"\n"`.
+//
+// `XGETCHAR` and `PROMPT` read from the same buffer (Stdin), so they can be freely mixed: After
+// `XGETCHAR` reads the first rune of a line, `PROMPT` returns the rest of it. However, when the
+// standard input is a terminal, the terminal itself buffers input a line at a time, so `XGETCHAR`
+// has to wait until the user presses enter (and the rest of that line is then kept in Stdin, for
+// the next `XGETCHAR` or `PROMPT`). Reading keys as they're pressed requires putting the terminal
+// into raw mode (eg via `stty raw`) before running the program.
+//
+// ## Examples
+//
+//	DUMP XGETCHAR <stdin="ab">                  #=> "a"
+//	DUMP XGETCHAR <stdin="αβ">                  #=> "α"
+//	DUMP ; XGETCHAR PROMPT <stdin="abc\nd">     #=> "bc"
+//	DUMP ; PROMPT XGETCHAR <stdin="a\nb">       #=> "b"
+//	DUMP XGETCHAR <stdin="\n">                  #=> "\n"
+//	DUMP XGETCHAR <stdin="">                    #=> null
+//
+// ## Undefined Behaviour
+// Errors reading from Stdin (other than the end of the input) are returned. Invalid UTF-8 is read
+// one byte at a time, and returned as the replacement character (U+FFFD).
+func getChar(_ []Value) (Value, error) {
+	r, _, err := Stdin.ReadRune()
+	if err == io.EOF {
+		return Null{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to 'XGETCHAR': %v", err)
+	}
+
+	return String(string(r)), nil
+}
+
+// put converts its argument to a string, and writes it to Stdout exactly as-is, returning Null.
+// Unlike `OUTPUT`, no newline is added, and a trailing `\` isn't removed. (It's also never limited
+// by OutputLinesPerSecond, as it doesn't write lines.)
+//
+// `XPUT` doesn't flush Stdout; when it's buffered, use `XFLUSH` afterwards to make sure partial
+// lines are visible immediately. Together with a carriage return (`A 13`), this lets programs
+// overwrite the current line of a terminal, such as for progress bars:
+//
+//	; = cr A 13
+//	; = i 0
+//	: WHILE (< i 5) ; XPUT + cr XPROGRESS (= i + i 1) 5 10 XFLUSH
+//
+// (With the default Stdout, the standard output, writes aren't buffered by Go, so `XFLUSH` isn't
+// strictly needed; but it ensures the program still works when Stdout is replaced by an embedder.)
+//
+// ## Examples
+//
+//	; XPUT "a" XPUT "b"     #=> ab
+//	XPUT "c:\"              #=> c:\
+//	XPUT 12                 #=> 12
+//
+// ## Undefined Behaviour
+// Types which can't be converted to strings yield an error:
+//
+//	XPUT BLOCK foo          #!! error: cant convert to a string
+//
+// Any errors with writing to Stdout are silently ignored.
+func put(args []Value) (Value, error) {
+	message, err := executeToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// (The error is explicitly ignored to be consistent with how `OUTPUT` works.)
+	_, _ = io.WriteString(Stdout, message)
+	return Null{}, nil
+}
+
+// arguments returns Args (the program's command-line arguments) as a list of strings.
+//
+// ## Examples
+//
+//	DUMP XARGS <args="a b">     #=> ["a", "b"]
+//	DUMP XARGS <args="">        #=> []
+func arguments(_ []Value) (Value, error) {
+	list := make(List, len(Args))
+
+	for i, arg := range Args {
+		list[i] = String(arg)
+	}
+
+	return list, nil
+}
+
+// readFile converts its argument to a string, and returns the contents of the file at that path.
+// Relative paths are relative to the current working directory. Unlike the backtick function (eg
+// with `cat`), the contents are returned exactly as-is, including any trailing newline.
+//
+// ## Examples
+//
+//	DUMP XREADFILE "hello.txt"     #=> "hello\n"  (if `hello.txt` contains `hello` and a newline)
+//	DUMP XREADFILE "empty.txt"     #=> ""
+//
+// ## Undefined Behaviour
+// Files which can't be read (eg because they don't exist, or due to permissions) yield an error,
+// as does calling `XREADFILE` when Sandboxed (or Deterministic) is enabled:
+//
+//	DUMP XREADFILE "missing.txt"   #!! error: no such file or directory
+//	DUMP XREADFILE "hello.txt"     #!! error: disabled  (when Sandboxed is enabled)
+func readFile(args []Value) (Value, error) {
+	if err := checkSandbox("XREADFILE"); err != nil {
+		return nil, err
+	}
+
+	path, err := executeToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to 'XREADFILE': %v", err)
+	}
+
+	return String(contents), nil
+}
+
+// writeFileFlags is a helper function for writeFile and appendFile. It converts path and contents to
+// strings, and then writes contents to the file at path, opened with the given flags (in addition to
+// `os.O_WRONLY` and `os.O_CREATE`). New files are created with permissions `0666` (before the
+// umask). The functionName argument is used for checking Sandboxed, and for error messages.
+func writeFileFlags(path, contents Value, flags int, functionName string) (Value, error) {
+	if err := checkSandbox(functionName); err != nil {
+		return nil, err
+	}
+
+	pathString, err := executeToString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	contentsString, err := executeToString(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(pathString, os.O_WRONLY|os.O_CREATE|flags, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("unable to '%s': %v", functionName, err)
+	}
+
+	_, err = file.WriteString(contentsString)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to '%s': %v", functionName, err)
+	}
+
+	return Null{}, nil
+}
+
+// writeFile converts both its arguments to strings, and then writes the second to the file at the
+// path given by the first, returning Null. If the file already exists, it's truncated first (so its
+// previous contents are replaced); otherwise, it's created. (Use `XAPPENDFILE` to add to the end of
+// a file instead.) Nothing is added to the contents, not even a trailing newline.
+//
+// ## Examples
+//
+//	XWRITEFILE "hello.txt" "hello"       # (`hello.txt` now contains just `hello`)
+//	; XWRITEFILE "a.txt" 12 : DUMP XREADFILE "a.txt"  #=> "12"
+//
+// ## Undefined Behaviour
+// Files which can't be written (eg because their directory doesn't exist, or due to permissions)
+// yield an error, as does calling `XWRITEFILE` when Sandboxed (or Deterministic) is enabled:
+//
+//	XWRITEFILE "missing/a.txt" "hi"      #!! error: no such file or directory
+//	XWRITEFILE "a.txt" "hi"              #!! error: disabled  (when Sandboxed is enabled)
+func writeFile(args []Value) (Value, error) {
+	return writeFileFlags(args[0], args[1], os.O_TRUNC, "XWRITEFILE")
+}
+
+// appendFile is like writeFile, except that the second argument is added to the end of the file
+// instead of replacing its contents. Like writeFile, the file is created if it doesn't exist.
+//
+// ## Examples
+//
+//	; XWRITEFILE "log.txt" "a" ; XAPPENDFILE "log.txt" "b" : DUMP XREADFILE "log.txt"  #=> "ab"
+//
+// ## Undefined Behaviour
+// See writeFile.
+func appendFile(args []Value) (Value, error) {
+	return writeFileFlags(args[0], args[1], os.O_APPEND, "XAPPENDFILE")
+}
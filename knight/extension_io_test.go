@@ -0,0 +1,148 @@
+package knight
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCapture(t *testing.T) {
+	defer func(stdout io.Writer) { Stdout = stdout }(Stdout)
+
+	var buffer bytes.Buffer
+	Stdout = &buffer
+
+	// The inner capture's output is only seen by the outer one because it's dumped.
+	result, err := Evaluate("XCAPTURE ; OUTPUT 1 ; DUMP XCAPTURE OUTPUT 2 : OUTPUT 3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := String("1\n\"2\\n\"3\n"); result != expected {
+		t.Errorf("expected %q, got %#v", expected, result)
+	}
+
+	if buffer.Len() != 0 {
+		t.Errorf("captured output was written to Stdout: %q", buffer.String())
+	}
+}
+
+func TestCaptureRestoresStdoutAfterErrors(t *testing.T) {
+	defer func(stdout io.Writer) { Stdout = stdout }(Stdout)
+
+	var buffer bytes.Buffer
+	Stdout = &buffer
+
+	var abortError *AbortError
+	if _, err := Evaluate("XCAPTURE ; OUTPUT 1 XABORT 2"); !errors.As(err, &abortError) {
+		t.Fatalf("expected an AbortError, got %v", err)
+	}
+
+	if Stdout != &buffer {
+		t.Fatalf("Stdout wasn't restored")
+	}
+
+	if _, err := Evaluate("OUTPUT 3"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if buffer.String() != "3\n" {
+		t.Errorf("expected %q, got %q", "3\n", buffer.String())
+	}
+}
+
+func TestTee(t *testing.T) {
+	defer func(stdout io.Writer) { Stdout = stdout }(Stdout)
+
+	var buffer bytes.Buffer
+	Stdout = &buffer
+
+	result, err := Evaluate("XTEE ; OUTPUT 1 DUMP 2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result != String("1\n2") {
+		t.Errorf("expected %q to be returned, got %#v", "1\n2", result)
+	}
+
+	if buffer.String() != "1\n2" {
+		t.Errorf("expected %q to be written, got %q", "1\n2", buffer.String())
+	}
+
+	if Stdout != &buffer {
+		t.Errorf("Stdout wasn't restored")
+	}
+}
+
+func TestPromptInt(t *testing.T) {
+	defer func(stdin *bufio.Reader, stderr io.Writer) { Stdin, Stderr = stdin, stderr }(Stdin, Stderr)
+
+	for _, test := range []struct {
+		stdin    string
+		expected Value
+		stderr   string
+	}{
+		{"12\n", Integer(12), ""},
+		{" -3 \n", Integer(-3), ""},
+		{"a\n4b\n5\n", Integer(5), strings.Repeat("please enter an integer\n", 2)},
+		{"a", Null{}, "please enter an integer\n"},
+		{"", Null{}, ""},
+	} {
+		var stderr bytes.Buffer
+		Stdin = bufio.NewReader(strings.NewReader(test.stdin))
+		Stderr = &stderr
+
+		result, err := Evaluate("XPROMPTINT")
+		if err != nil {
+			t.Errorf("stdin %q: unexpected error: %v", test.stdin, err)
+			continue
+		}
+
+		if result != test.expected {
+			t.Errorf("stdin %q: expected %#v, got %#v", test.stdin, test.expected, result)
+		}
+
+		if stderr.String() != test.stderr {
+			t.Errorf("stdin %q: expected stderr %q, got %q", test.stdin, test.stderr, stderr.String())
+		}
+	}
+}
+
+func TestPut(t *testing.T) {
+	defer func(stdout io.Writer) { Stdout = stdout }(Stdout)
+
+	for _, test := range []struct {
+		program  string
+		expected string
+	}{
+		{`XPUT "a\"`, `a\`},
+		{`; XPUT "a" XPUT "b"`, "ab"},
+		{`XPUT 12`, "12"},
+		{`XPUT ""`, ""},
+	} {
+		var buffer bytes.Buffer
+		Stdout = &buffer
+
+		result, err := Evaluate(test.program)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.program, err)
+			continue
+		}
+
+		if result != (Null{}) {
+			t.Errorf("%s: expected null, got %#v", test.program, result)
+		}
+
+		if buffer.String() != test.expected {
+			t.Errorf("%s: expected %q to be written, got %q", test.program, test.expected, buffer.String())
+		}
+	}
+
+	if _, err := Evaluate("XPUT BLOCK a"); err == nil {
+		t.Error("expected an error for a block")
+	}
+}
@@ -0,0 +1,604 @@
+package knight
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ClampTakeAndDrop controls what `XTAKE` and `XDROP` do when they're given an amount larger than the
+// length of their container. When true (the default), the amount is clamped to the length; when
+// false, an error is returned instead.
+var ClampTakeAndDrop = true
+
+// StrictZip controls what `XZIP` does when it's given lists of different lengths. When false (the
+// default), the extra elements of the longer list are ignored; when true, an error is returned.
+var StrictZip = false
+
+// Register the list extension functions. (See `init` in `function.go` for more details.)
+func init() {
+	ExtensionFunctions["XTAKE"] = &Function{name: "XTAKE", arity: 2, fn: take}
+	ExtensionFunctions["XDROP"] = &Function{name: "XDROP", arity: 2, fn: drop}
+	ExtensionFunctions["XZIP"] = &Function{name: "XZIP", arity: 2, fn: zip}
+	ExtensionFunctions["XUNZIP"] = &Function{name: "XUNZIP", arity: 1, fn: unzip}
+	ExtensionFunctions["XCOUNT"] = &Function{name: "XCOUNT", arity: 2, fn: count}
+	ExtensionFunctions["XAT"] = &Function{name: "XAT", arity: 2, fn: at}
+	ExtensionFunctions["XJOIN"] = &Function{name: "XJOIN", arity: 2, fn: join}
+	ExtensionFunctions["XEMPTY"] = &Function{name: "XEMPTY", arity: 1, fn: empty}
+	ExtensionFunctions["XSAMPLE"] = &Function{name: "XSAMPLE", arity: 1, fn: sample}
+	ExtensionFunctions["XMEMBER"] = &Function{name: "XMEMBER", arity: 3, fn: member}
+	ExtensionFunctions["XMAXINDEX"] = &Function{name: "XMAXINDEX", arity: 1, fn: maxIndex}
+	ExtensionFunctions["XMININDEX"] = &Function{name: "XMININDEX", arity: 1, fn: minIndex}
+	ExtensionFunctions["XCHUNK"] = &Function{name: "XCHUNK", arity: 2, fn: chunk}
+}
+
+// takeOrDropAmount is a helper function for take and drop. It executes amount and converts it to an
+// integer, returning an error if it's negative. If the result is larger than length, then either
+// length is returned or an error is, depending on ClampTakeAndDrop.
+func takeOrDropAmount(amount Value, length int, functionName string) (int, error) {
+	n, err := executeToInt(amount)
+	if err != nil {
+		return 0, err
+	}
+
+	if n < 0 {
+		return 0, fmt.Errorf("negative amount given to '%s': %d", functionName, n)
+	}
+
+	if length < n {
+		if !ClampTakeAndDrop {
+			return 0, fmt.Errorf("amount out of bounds for '%s': %d < %d", functionName, length, n)
+		}
+
+		n = length
+	}
+
+	return n, nil
+}
+
+// take returns the first `n` elements/runes of a list/string. It returns an error if `n` is
+// negative, or if the first argument isn't a list or string.
+//
+// ## Examples
+//
+//	DUMP XTAKE "abcde" 2    #=> "ab"
+//	DUMP XTAKE "abcde" 0    #=> ""
+//	DUMP XTAKE (+@123) 2    #=> [1, 2]
+//	DUMP XTAKE "😁😁😁" 2   #=> "😁😁"
+//
+// ## Undefined Behaviour
+// Amounts larger than the container's length are clamped to its length by default (see
+// ClampTakeAndDrop):
+//
+//	DUMP XTAKE "abc" 10     #=> "abc"
+//
+// Other forms of undefined behaviour yield errors:
+//
+//	DUMP XTAKE "abc" ~1     #!! error, negative amount
+//	DUMP XTAKE TRUE 1       #!! error, invalid type
+func take(args []Value) (Value, error) {
+	collection, err := args[0].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	switch collection := collection.(type) {
+	case String:
+		n, err := takeOrDropAmount(args[1], stringLength(collection), "XTAKE")
+		if err != nil {
+			return nil, err
+		}
+
+		return substring(collection, 0, n), nil
+
+	case List:
+		n, err := takeOrDropAmount(args[1], len(collection), "XTAKE")
+		if err != nil {
+			return nil, err
+		}
+
+		// (The capacity is limited so that appending to the result can't modify the original list.)
+		return collection[:n:n], nil
+
+	default:
+		return nil, fmt.Errorf("invalid type given to 'XTAKE': %T", collection)
+	}
+}
+
+// drop returns everything but the first `n` elements/runes of a list/string. It returns an error if
+// `n` is negative, or if the first argument isn't a list or string.
+//
+// ## Examples
+//
+//	DUMP XDROP "abcde" 2    #=> "cde"
+//	DUMP XDROP "abcde" 0    #=> "abcde"
+//	DUMP XDROP (+@123) 2    #=> [3]
+//	DUMP XDROP "😁😁😁" 2   #=> "😁"
+//
+// ## Undefined Behaviour
+// Amounts larger than the container's length are clamped to its length by default (see
+// ClampTakeAndDrop):
+//
+//	DUMP XDROP "abc" 10     #=> ""
+//
+// Other forms of undefined behaviour yield errors:
+//
+//	DUMP XDROP "abc" ~1     #!! error, negative amount
+//	DUMP XDROP TRUE 1       #!! error, invalid type
+func drop(args []Value) (Value, error) {
+	collection, err := args[0].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	switch collection := collection.(type) {
+	case String:
+		n, err := takeOrDropAmount(args[1], stringLength(collection), "XDROP")
+		if err != nil {
+			return nil, err
+		}
+
+		return substringFrom(collection, n), nil
+
+	case List:
+		n, err := takeOrDropAmount(args[1], len(collection), "XDROP")
+		if err != nil {
+			return nil, err
+		}
+
+		return collection[n:], nil
+
+	default:
+		return nil, fmt.Errorf("invalid type given to 'XDROP': %T", collection)
+	}
+}
+
+// zip converts both its arguments to lists, and then returns a list of two-element lists, each of
+// which contains the corresponding elements from the two lists.
+//
+// ## Examples
+//
+//	DUMP XZIP (+@123) "abc"  #=> [[1, "a"], [2, "b"], [3, "c"]]
+//	DUMP XZIP @ @            #=> []
+//
+// ## Undefined Behaviour
+// By default, if the lists have different lengths, the extra elements of the longer one are ignored.
+// If StrictZip is enabled, an error is returned instead:
+//
+//	DUMP XZIP (+@123) "ab"   #=> [[1, "a"], [2, "b"]]
+//	DUMP XZIP (+@123) "ab"   #!! error, length mismatch (when StrictZip is enabled)
+//
+// Types which can't be converted to lists yield an error:
+//
+//	DUMP XZIP (BLOCK foo) @  #!! error: cant convert to a list
+func zip(args []Value) (Value, error) {
+	lhs, err := executeToSlice(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	rhs, err := executeToSlice(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if StrictZip && len(lhs) != len(rhs) {
+		return nil, fmt.Errorf("length mismatch for 'XZIP': %d != %d", len(lhs), len(rhs))
+	}
+
+	minLen := len(lhs)
+	if len(rhs) < minLen {
+		minLen = len(rhs)
+	}
+
+	zipped := make(List, minLen)
+	for i := range zipped {
+		zipped[i] = List{lhs[i], rhs[i]}
+	}
+
+	return zipped, nil
+}
+
+// unzip is the inverse of zip: It converts its argument to a list, which should only contain two-
+// element lists, and returns a two-element list of a list of all their first elements and a list of
+// all their second elements.
+//
+// ## Examples
+//
+//	DUMP XUNZIP XZIP (+@123) "abc"      #=> [[1, 2, 3], ["a", "b", "c"]]
+//	DUMP XUNZIP @                       #=> [[], []]
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XUNZIP` yield errors:
+//
+//	DUMP XUNZIP ,(+@123)                #!! error: not a pair
+//	DUMP XUNZIP +@12                    #!! error: not a pair
+func unzip(args []Value) (Value, error) {
+	pairs, err := executeToSlice(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	firsts := make(List, len(pairs))
+	seconds := make(List, len(pairs))
+
+	for i, element := range pairs {
+		pair, ok := element.(List)
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("element %d given to 'XUNZIP' is not a pair: %s", i, dumpToString(element))
+		}
+
+		firsts[i], seconds[i] = pair[0], pair[1]
+	}
+
+	return List{firsts, seconds}, nil
+}
+
+// count returns how many times the second argument occurs in the first. For strings, the second
+// argument is converted to a string, and the number of non-overlapping occurrences of it is
+// returned. For lists, the number of elements equal to the second argument (using the same
+// semantics as `?`, so there's no coercion) is returned.
+//
+// ## Examples
+//
+//	DUMP XCOUNT "banana" "a"        #=> 3
+//	DUMP XCOUNT "aaaa" "aa"         #=> 2   (occurrences don't overlap)
+//	DUMP XCOUNT "αβ" ""             #=> 3   (the empty string occurs before and after every rune)
+//	DUMP XCOUNT (+@1213) 1          #=> 2
+//	DUMP XCOUNT (+@1213) "1"        #=> 0   (no coercion is done for lists)
+//
+// ## Undefined Behaviour
+// Other types yield an error:
+//
+//	DUMP XCOUNT TRUE 1              #!! error, invalid type
+func count(args []Value) (Value, error) {
+	collection, err := args[0].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	switch collection := collection.(type) {
+	case String:
+		needle, err := executeToString(args[1])
+		if err != nil {
+			return nil, err
+		}
+
+		return Integer(strings.Count(string(collection), needle)), nil
+
+	case List:
+		needle, err := args[1].Execute()
+		if err != nil {
+			return nil, err
+		}
+
+		occurrences := 0
+		for _, element := range collection {
+			if reflect.DeepEqual(element, needle) {
+				occurrences++
+			}
+		}
+
+		return Integer(occurrences), nil
+
+	default:
+		return nil, fmt.Errorf("invalid type given to 'XCOUNT': %T", collection)
+	}
+}
+
+// at returns the element/rune at the given index of a list/string. Unlike `GET`, which always returns
+// a sublist/substring, this returns the element itself. It returns an error if the index is
+// negative or out of bounds, or if the first argument isn't a list or string.
+//
+// ## Examples
+//
+//	DUMP XAT (+@123) 0     #=> 1
+//	DUMP XAT ,,1 0         #=> [1]
+//	DUMP XAT "abc" 2       #=> "c"
+//	DUMP XAT "😁😁😁" 1    #=> "😁"
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XAT` yield errors:
+//
+//	DUMP XAT "abc" 3       #!! error, string index out of bounds
+//	DUMP XAT (+@123) ~1    #!! error, negative index
+//	DUMP XAT TRUE 0        #!! error, invalid type
+func at(args []Value) (Value, error) {
+	collection, err := args[0].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := executeToInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("negative index given to 'XAT': %d", index)
+	}
+
+	switch collection := collection.(type) {
+	case String:
+		if stringLength(collection) <= index {
+			return nil, fmt.Errorf("string index out of bounds for 'XAT': %d <= %d",
+				stringLength(collection), index)
+		}
+
+		return substring(collection, index, index+1), nil
+
+	case List:
+		if len(collection) <= index {
+			return nil, fmt.Errorf("list index out of bounds for 'XAT': %d <= %d", len(collection), index)
+		}
+
+		return collection[index], nil
+
+	default:
+		return nil, fmt.Errorf("invalid type given to 'XAT': %T", collection)
+	}
+}
+
+// join converts its first argument to a list and its second to a string, and then returns the
+// list's elements (converted to strings) joined together by the string. It's the same as `^` when
+// given a list, but makes the intent clearer in the source code.
+//
+// ## Examples
+//
+//	DUMP XJOIN (+@123) ", "  #=> "1, 2, 3"
+//	DUMP XJOIN @ ":"         #=> ""
+//	DUMP XJOIN "abc" "-"     #=> "a-b-c"
+//
+// ## Undefined Behaviour
+// Types which can't be converted to strings, either in the list or as the separator, yield an
+// error:
+//
+//	DUMP XJOIN ,BLOCK a ""   #!! error: cant convert to a string
+func join(args []Value) (Value, error) {
+	list, err := executeToSlice(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	separator, err := executeToString(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	joined, err := list.Join(separator)
+	if err != nil {
+		return nil, err
+	}
+
+	return String(joined), nil
+}
+
+// empty returns whether its argument is an empty list or an empty string. Unlike `!`, which also
+// treats `0`, `FALSE`, and `NULL` as "empty", this only accepts lists and strings, so the intent is
+// clearer in the source code.
+//
+// ## Examples
+//
+//	DUMP XEMPTY @        #=> true
+//	DUMP XEMPTY ""       #=> true
+//	DUMP XEMPTY ,@       #=> false
+//	DUMP XEMPTY "0"      #=> false
+//
+// ## Undefined Behaviour
+// Other types yield an error:
+//
+//	DUMP XEMPTY 0        #!! error, invalid type
+//	DUMP XEMPTY NULL     #!! error, invalid type
+func empty(args []Value) (Value, error) {
+	collection, err := args[0].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	switch collection := collection.(type) {
+	case String:
+		return Boolean(len(collection) == 0), nil
+
+	case List:
+		return Boolean(len(collection) == 0), nil
+
+	default:
+		return nil, fmt.Errorf("invalid type given to 'XEMPTY': %T", collection)
+	}
+}
+
+// sample converts its argument to a list, and returns a random element of it. Each element is
+// equally likely to be chosen. It uses the same random number generator as `RANDOM`.
+//
+// ## Examples
+//
+//	DUMP XSAMPLE +@123   #=> 2  (or 1, or 3)
+//	DUMP XSAMPLE "abc"   #=> "c"  (or "a", or "b")
+//	DUMP XSAMPLE ,TRUE   #=> true
+//
+// ## Undefined Behaviour
+// Empty lists yield an error:
+//
+//	DUMP XSAMPLE @       #!! error: empty list
+func sample(args []Value) (Value, error) {
+	list, err := executeToSlice(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, errors.New("empty list given to 'XSAMPLE'")
+	}
+
+	// (`rand.Intn` doesn't have the bias that `% RANDOM LENGTH list` would.)
+	return list[randomIntn(len(list))], nil
+}
+
+// member converts its first argument to a list, and returns whether any of its elements "match" the
+// second argument, according to the third. This is like checking whether `XCOUNT` is nonzero, except
+// that the third argument decides what counts as equal, rather than `?`.
+//
+// The first and second arguments are executed once, in that order. Then, for each element (in
+// order), the element is assigned to the variable `_1` and the second argument's result to `_2`,
+// and the third argument is executed and converted to a boolean. As soon as it's true, `TRUE` is
+// returned, without checking the remaining elements. (Like `WHILE`'s body, the third argument is
+// executed directly; to use a block stored in a variable, use `CALL`.)
+//
+// ## Examples
+//
+//	DUMP XMEMBER (+@123) 2 (? _1 _2)                      #=> true
+//	DUMP XMEMBER (+@123) "2" (? _1 _2)                    #=> false
+//	; = loosely BLOCK ? _1 (+ 0 _2)
+//	: DUMP XMEMBER (+@123) "2" CALL loosely               #=> true
+//	DUMP XMEMBER (+@123) 2 (< _2 _1)                      #=> true  (is any element above 2?)
+//	DUMP XMEMBER @ 1 TRUE                                 #=> false
+//
+// ## Undefined Behaviour
+// Types which can't be converted to lists yield an error:
+//
+//	DUMP XMEMBER (BLOCK a) 1 TRUE                         #!! error: cant convert to a list
+func member(args []Value) (Value, error) {
+	list, err := executeToSlice(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := args[1].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, element := range list {
+		// See `while` for why this is needed.
+		if err := checkDeadline(); err != nil {
+			return nil, err
+		}
+
+		bindArguments(element, target)
+
+		matches, err := executeToBool(args[2])
+		if err != nil {
+			return nil, err
+		}
+
+		if matches {
+			return Boolean(true), nil
+		}
+	}
+
+	return Boolean(false), nil
+}
+
+// extremeIndex is a helper function for maxIndex and minIndex. It executes value, converts it to a
+// list, and returns the index of the first element that every other element compares (via
+// `compare`, like `<` and `>` do) as less than or equal to, if sign is `1`, or greater than or
+// equal to, if sign is `-1`. The functionName argument is just used for error messages.
+func extremeIndex(value Value, sign int, functionName string) (Value, error) {
+	list, err := executeToSlice(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, fmt.Errorf("empty list given to '%s'", functionName)
+	}
+
+	// (The first element is compared against itself, so that it's an error even if it's the only
+	// element and can't be compared.)
+	best := 0
+	for i, element := range list {
+		cmp, err := compare(element, list[best], functionName)
+		if err != nil {
+			return nil, err
+		}
+
+		if (0 < sign && 0 < cmp) || (sign < 0 && cmp < 0) {
+			best = i
+		}
+	}
+
+	return Integer(best), nil
+}
+
+// maxIndex converts its argument to a list, and returns the index of its largest element. If
+// multiple elements are the largest, the first one's index is returned. Elements are compared the
+// same way `>` compares them, so later elements are coerced to the type of the ones they're
+// compared against.
+//
+// ## Examples
+//
+//	DUMP XMAXINDEX +@"bca"                  #=> 1
+//	DUMP XMAXINDEX ,3                       #=> 0
+//	DUMP XMAXINDEX +@1331                   #=> 1   (the first of the ties)
+//	DUMP XMAXINDEX +,(+@12),(+@3)           #=> 1   (lists are compared element-wise)
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XMAXINDEX` yield errors:
+//
+//	DUMP XMAXINDEX @                        #!! error: empty list
+//	DUMP XMAXINDEX ,BLOCK a                 #!! error: invalid type
+func maxIndex(args []Value) (Value, error) {
+	return extremeIndex(args[0], 1, "XMAXINDEX")
+}
+
+// minIndex converts its argument to a list, and returns the index of its smallest element. If
+// multiple elements are the smallest, the first one's index is returned. Elements are compared the
+// same way `<` compares them.
+//
+// ## Examples
+//
+//	DUMP XMININDEX +@"bca"                  #=> 2
+//	DUMP XMININDEX +@3113                   #=> 1   (the first of the ties)
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XMININDEX` yield errors:
+//
+//	DUMP XMININDEX @                        #!! error: empty list
+func minIndex(args []Value) (Value, error) {
+	return extremeIndex(args[0], -1, "XMININDEX")
+}
+
+// chunk converts its first argument to a list and its second to an integer, and returns a list of
+// consecutive sublists of the first argument, each with that many elements. If the list's length
+// isn't a multiple of the size, the last sublist has the leftover elements.
+//
+// ## Examples
+//
+//	DUMP XCHUNK (+@12345) 2     #=> [[1, 2], [3, 4], [5]]
+//	DUMP XCHUNK (+@123) 3       #=> [[1, 2, 3]]
+//	DUMP XCHUNK "abc" 5         #=> [["a", "b", "c"]]
+//	DUMP XCHUNK @ 2             #=> []
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XCHUNK` yield errors:
+//
+//	DUMP XCHUNK (+@123) 0       #!! error: non-positive size
+func chunk(args []Value) (Value, error) {
+	list, err := executeToSlice(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := executeToInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if size <= 0 {
+		return nil, fmt.Errorf("non-positive size given to 'XCHUNK': %d", size)
+	}
+
+	chunks := List{}
+	for start := 0; start < len(list); start += size {
+		stop := len(list)
+		if size < stop-start {
+			stop = start + size
+		}
+
+		// Use a full slice expression so that each chunk has its own capacity, and so can never
+		// share elements appended to another chunk.
+		chunks = append(chunks, list[start:stop:stop])
+	}
+
+	return chunks, nil
+}
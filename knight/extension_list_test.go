@@ -0,0 +1,227 @@
+package knight
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestZip(t *testing.T) {
+	for _, test := range []struct {
+		lhs, rhs Value
+		expected List
+	}{
+		{List{Integer(1), Integer(2)}, String("ab"), List{
+			List{Integer(1), String("a")},
+			List{Integer(2), String("b")},
+		}},
+		{List{Integer(1), Integer(2), Integer(3)}, String("ab"), List{
+			List{Integer(1), String("a")},
+			List{Integer(2), String("b")},
+		}},
+		{List{Integer(1)}, String("abc"), List{List{Integer(1), String("a")}}},
+		{List{}, String("abc"), List{}},
+		{List{}, List{}, List{}},
+	} {
+		result, err := zip([]Value{test.lhs, test.rhs})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("expected %s, got %s", dumpToString(test.expected), dumpToString(result))
+		}
+	}
+}
+
+func TestStrictZip(t *testing.T) {
+	StrictZip = true
+	defer func() { StrictZip = false }()
+
+	if _, err := zip([]Value{List{Integer(1), Integer(2)}, String("a")}); err == nil {
+		t.Error("expected an error for lists of different lengths")
+	}
+
+	result, err := zip([]Value{List{}, List{}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(result, List{}) {
+		t.Errorf("expected [], got %s", dumpToString(result))
+	}
+}
+
+func TestTakeDoesntShareCapacity(t *testing.T) {
+	original := List{Integer(1), Integer(2), Integer(3)}
+
+	result, err := take([]Value{original, Integer(2)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = append(result.(List), Integer(4))
+
+	if original[2] != Integer(3) {
+		t.Errorf("appending to the result modified the original list: %s", dumpToString(original))
+	}
+}
+
+func TestCount(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{`XCOUNT "banana" "a"`, Integer(3)},
+		{`XCOUNT "aaaa" "aa"`, Integer(2)},
+		{`XCOUNT "aaa" "aa"`, Integer(1)},
+		{`XCOUNT "abc" "d"`, Integer(0)},
+		{`XCOUNT "αβ" ""`, Integer(3)},
+		{`XCOUNT "" ""`, Integer(1)},
+		{`XCOUNT (+@1213) 1`, Integer(2)},
+		{`XCOUNT (+@1213) "1"`, Integer(0)},
+		{`XCOUNT (+ ,,1 ,,1) ,1`, Integer(2)},
+		{`XCOUNT @ 1`, Integer(0)},
+		{`XCOUNT TRUE 1`, nil},
+	})
+}
+
+func TestJoin(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{`XJOIN (+@123) ", "`, String("1, 2, 3")},
+		{`XJOIN (+@123) ""`, String("123")},
+		{`XJOIN ,"a" "-"`, String("a")},
+		{`XJOIN @ ":"`, String("")},
+		{`XJOIN "abc" "-"`, String("a-b-c")},
+		{`XJOIN (+ ,TRUE ,NULL) "/"`, String("true/")},
+		{`XJOIN ,BLOCK a ""`, nil},
+		{`XJOIN (+ ,1 ,BLOCK a) ", "`, nil},
+	})
+}
+
+func TestEmpty(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{`XEMPTY @`, Boolean(true)},
+		{`XEMPTY ""`, Boolean(true)},
+		{`XEMPTY ,@`, Boolean(false)},
+		{`XEMPTY ,1`, Boolean(false)},
+		{`XEMPTY "0"`, Boolean(false)},
+		{`XEMPTY " "`, Boolean(false)},
+		{`XEMPTY 0`, nil},
+		{`XEMPTY FALSE`, nil},
+		{`XEMPTY NULL`, nil},
+	})
+}
+
+func TestSample(t *testing.T) {
+	defer func(deterministic bool) { Deterministic = deterministic }(Deterministic)
+	Deterministic = true
+
+	function, err := Parse(`XSAMPLE "abc"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Each element has a 1/3 chance of being chosen, so all of them should show up well before 100.
+	seen := make(map[Value]bool)
+	for i := 0; i < 100 && len(seen) < 3; i++ {
+		result, err := function.Execute()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if result != String("a") && result != String("b") && result != String("c") {
+			t.Fatalf("unexpected result: %#v", result)
+		}
+
+		seen[result] = true
+	}
+
+	if len(seen) != 3 {
+		t.Errorf("not every element was sampled: %v", seen)
+	}
+
+	runEvaluateTests(t, []evaluateTest{
+		{`XSAMPLE ,TRUE`, Boolean(true)},
+		{`XSAMPLE @`, nil},
+		{`XSAMPLE ""`, nil},
+	})
+}
+
+func TestMaxIndexAndMinIndex(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{`XMAXINDEX +@"bca"`, Integer(1)},
+		{`XMAXINDEX ,3`, Integer(0)},
+		{`XMAXINDEX +@1331`, Integer(1)},
+		{`XMAXINDEX +@333`, Integer(0)},
+		{`XMAXINDEX +,(+@12),(+@3)`, Integer(1)},
+		{`XMAXINDEX @`, nil},
+		{`XMAXINDEX ,BLOCK a`, nil},
+
+		{`XMININDEX +@"bca"`, Integer(2)},
+		{`XMININDEX ,3`, Integer(0)},
+		{`XMININDEX +@3113`, Integer(1)},
+		{`XMININDEX +@333`, Integer(0)},
+		{`XMININDEX @`, nil},
+		{`XMININDEX ,BLOCK a`, nil},
+	})
+}
+
+func TestChunk(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{`XCHUNK (+@12345) 2`, List{
+			List{Integer(1), Integer(2)},
+			List{Integer(3), Integer(4)},
+			List{Integer(5)},
+		}},
+		{`XCHUNK (+@123456) 3`, List{
+			List{Integer(1), Integer(2), Integer(3)},
+			List{Integer(4), Integer(5), Integer(6)},
+		}},
+		{`XCHUNK (+@123) 3`, List{List{Integer(1), Integer(2), Integer(3)}}},
+		{`XCHUNK (+@123) 1`, List{List{Integer(1)}, List{Integer(2)}, List{Integer(3)}}},
+		{`XCHUNK "abc" 5`, List{List{String("a"), String("b"), String("c")}}},
+		{`XCHUNK @ 2`, List{}},
+		{`XCHUNK (+@123) 0`, nil},
+		{`XCHUNK (+@123) ~1`, nil},
+	})
+}
+
+func TestChunkDoesntAlias(t *testing.T) {
+	input := List{Integer(1), Integer(2), Integer(3), Integer(4)}
+	SetVariable("chunk_input", input)
+
+	result, err := Evaluate("XCHUNK chunk_input 2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	chunks := result.(List)
+	first := chunks[0].(List)
+
+	// Appending to the first chunk mustn't overwrite the second chunk, or the input.
+	_ = append(first, String("x"))
+
+	if expected := (List{Integer(3), Integer(4)}); !reflect.DeepEqual(chunks[1], expected) {
+		t.Errorf("second chunk was modified: %#v", chunks[1])
+	}
+
+	expected := List{Integer(1), Integer(2), Integer(3), Integer(4)}
+	if !reflect.DeepEqual(input, expected) {
+		t.Errorf("input was modified: %#v", input)
+	}
+}
+
+func TestUnzip(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{`XUNZIP XZIP (+@123) "abc"`, List{
+			List{Integer(1), Integer(2), Integer(3)},
+			List{String("a"), String("b"), String("c")},
+		}},
+		{`XUNZIP ,+,1 ,2`, List{List{Integer(1)}, List{Integer(2)}}},
+		{`XUNZIP @`, List{List{}, List{}}},
+		{`XUNZIP ,(+@123)`, nil},
+		{`XUNZIP ,,1`, nil},
+		{`XUNZIP ,@`, nil},
+		{`XUNZIP +@12`, nil},
+		{`XUNZIP ,"ab"`, nil},
+		{`XUNZIP +,(+@12) ,3`, nil},
+	})
+}
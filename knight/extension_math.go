@@ -0,0 +1,728 @@
+package knight
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Register the math extension functions. (See `init` in `function.go` for more details.)
+func init() {
+	ExtensionFunctions["XTOBASE"] = &Function{name: "XTOBASE", arity: 2, fn: toBase}
+	ExtensionFunctions["XFROMBASE"] = &Function{name: "XFROMBASE", arity: 2, fn: fromBase}
+	ExtensionFunctions["XFIB"] = &Function{name: "XFIB", arity: 1, fn: fibonacci}
+	ExtensionFunctions["XCALC"] = &Function{name: "XCALC", arity: 1, fn: calculate}
+	ExtensionFunctions["XGROUPDIGITS"] = &Function{name: "XGROUPDIGITS", arity: 2, fn: groupDigits}
+	ExtensionFunctions["XFROMDIGITS"] = &Function{name: "XFROMDIGITS", arity: 1, fn: fromDigits}
+	ExtensionFunctions["XGCD"] = &Function{name: "XGCD", arity: 2, fn: gcd}
+	ExtensionFunctions["XLCM"] = &Function{name: "XLCM", arity: 2, fn: lcm}
+	ExtensionFunctions["XPRIME"] = &Function{name: "XPRIME", arity: 1, fn: prime}
+	ExtensionFunctions["XDIVIDES"] = &Function{name: "XDIVIDES", arity: 2, fn: divides}
+	ExtensionFunctions["XEVEN"] = &Function{name: "XEVEN", arity: 1, fn: even}
+	ExtensionFunctions["XODD"] = &Function{name: "XODD", arity: 1, fn: odd}
+	ExtensionFunctions["XCLAMP"] = &Function{name: "XCLAMP", arity: 3, fn: clamp}
+	ExtensionFunctions["XSIGN"] = &Function{name: "XSIGN", arity: 1, fn: sign}
+}
+
+// checkBase is a helper function which returns an error if base isn't 2, 8, or 16. The
+// functionName argument is just used for error messages.
+func checkBase(base int, functionName string) error {
+	if base != 2 && base != 8 && base != 16 {
+		return fmt.Errorf("unsupported base given to '%s': %d", functionName, base)
+	}
+
+	return nil
+}
+
+// toBase converts both its arguments to integers, and then returns the string representation of the
+// first in the base given by the second. Only bases 2, 8, and 16 are supported. (Integer.ToString
+// always uses base-10, as the Knight spec requires; this is an explicit extension.)
+//
+// ## Examples
+//
+//	DUMP XTOBASE 10 2    #=> "1010"
+//	DUMP XTOBASE 255 16  #=> "ff"
+//	DUMP XTOBASE ~8 8    #=> "-10"
+//
+// ## Undefined Behaviour
+// Other bases yield an error:
+//
+//	DUMP XTOBASE 10 10   #!! error: unsupported base
+func toBase(args []Value) (Value, error) {
+	integer, err := executeToInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := executeToInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkBase(base, "XTOBASE"); err != nil {
+		return nil, err
+	}
+
+	return String(strconv.FormatInt(int64(integer), base)), nil
+}
+
+// fromBase converts its first argument to a string and its second to an integer, and then parses
+// the string as an integer in that base. Only bases 2, 8, and 16 are supported.
+//
+// Unlike converting a string to an integer normally (which ignores trailing garbage and uses zero
+// when there's no integer at all), this is strict: An error is returned unless the entire string is
+// an integer (with an optional leading `+` or `-`) in the given base.
+//
+// ## Examples
+//
+//	DUMP XFROMBASE "1010" 2   #=> 10
+//	DUMP XFROMBASE "fF" 16    #=> 255
+//	DUMP XFROMBASE "-10" 8    #=> -8
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XFROMBASE` yield errors:
+//
+//	DUMP XFROMBASE "12" 2     #!! error: malformed integer
+//	DUMP XFROMBASE " 1" 2     #!! error: malformed integer (even whitespace isn't allowed)
+//	DUMP XFROMBASE "" 2       #!! error: malformed integer
+//	DUMP XFROMBASE "10" 10    #!! error: unsupported base
+func fromBase(args []Value) (Value, error) {
+	source, err := executeToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := executeToInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkBase(base, "XFROMBASE"); err != nil {
+		return nil, err
+	}
+
+	integer, err := strconv.ParseInt(source, base, strconv.IntSize)
+	if err != nil {
+		return nil, fmt.Errorf("malformed integer given to 'XFROMBASE': %q", source)
+	}
+
+	return Integer(integer), nil
+}
+
+// fibonacci converts its argument to an integer `n`, and returns the `n`th Fibonacci number, where
+// the zeroth is `0` and the first is `1`. It takes time proportional to `n`.
+//
+// Unlike the arithmetic functions, which just wrap around, an error is returned if the result is
+// too large to fit in an integer. (With 64-bit integers, `XFIB 92` is the largest that fits.)
+//
+// ## Examples
+//
+//	DUMP XFIB 0    #=> 0
+//	DUMP XFIB 1    #=> 1
+//	DUMP XFIB 10   #=> 55
+//	DUMP XFIB 92   #=> 7540113804746346429
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XFIB` yield errors:
+//
+//	DUMP XFIB ~1   #!! error: negative index
+//	DUMP XFIB 93   #!! error: result is too large
+func fibonacci(args []Value) (Value, error) {
+	n, err := executeToInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 0 {
+		return nil, fmt.Errorf("negative index given to 'XFIB': %d", n)
+	}
+
+	// `current` is the `i`th Fibonacci number, and `next` is the `i+1`th.
+	current, next := 0, 1
+	for i := 0; i < n; i++ {
+		// (The new `next` isn't used on the last iteration, so it's fine if that one overflows.)
+		if i+1 < n && math.MaxInt-current < next {
+			return nil, fmt.Errorf("result is too large for 'XFIB': %d", n)
+		}
+
+		current, next = next, current+next
+	}
+
+	return Integer(current), nil
+}
+
+// groupDigits converts its first argument to an integer and its second to a string, and then
+// returns the integer in base-10 with the string inserted between each group of exactly three
+// digits, counting from the right (eg `1,234,567`). For negative integers, the `-` comes first, and
+// isn't part of any group.
+//
+// ## Examples
+//
+//	DUMP XGROUPDIGITS 1234567 ","    #=> "1,234,567"
+//	DUMP XGROUPDIGITS ~1234 "."      #=> "-1.234"
+//	DUMP XGROUPDIGITS 123 ","        #=> "123"
+//	DUMP XGROUPDIGITS 1000000 " "    #=> "1 000 000"
+//	DUMP XGROUPDIGITS 12345 ""       #=> "12345"
+func groupDigits(args []Value) (Value, error) {
+	integer, err := executeToInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	separator, err := executeToString(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	// (We use the string's sign rather than the integer's, as the smallest integer can't be negated.)
+	digits := strconv.Itoa(integer)
+	sign := ""
+	if digits[0] == '-' {
+		sign, digits = "-", digits[1:]
+	}
+
+	var builder strings.Builder
+	builder.WriteString(sign)
+
+	for i, digit := range digits {
+		// Add a separator before each group of three, except the first.
+		if i != 0 && (len(digits)-i)%3 == 0 {
+			builder.WriteString(separator)
+		}
+
+		builder.WriteRune(digit)
+	}
+
+	return String(builder.String()), nil
+}
+
+// fromDigits converts its argument to a list of base-10 digits, and returns the integer they form.
+// It's the inverse of converting an integer to a list (eg `+ @ 123`), including for negative
+// integers, whose digits are all negative (eg `+ @ ~12` is `[-1, -2]`). So, all the digits must
+// either be `0` through `9`, or `0` through `-9`.
+//
+// ## Examples
+//
+//	DUMP XFROMDIGITS +,1 ,2         #=> 12
+//	DUMP XFROMDIGITS + @ 1234       #=> 1234
+//	DUMP XFROMDIGITS + @ ~1234      #=> -1234
+//	DUMP XFROMDIGITS ,0             #=> 0
+//	DUMP XFROMDIGITS +,0 ,7         #=> 7  (leading zeros are fine)
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XFROMDIGITS` yield errors:
+//
+//	DUMP XFROMDIGITS @              #!! error: empty list
+//	DUMP XFROMDIGITS ,10            #!! error: invalid digit
+//	DUMP XFROMDIGITS +,1 ,~2        #!! error: invalid digit (the signs must all match)
+//	DUMP XFROMDIGITS ,"1"           #!! error: invalid type
+//	DUMP XFROMDIGITS *,9 20         #!! error: result is too large
+func fromDigits(args []Value) (Value, error) {
+	digits, err := executeToSlice(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(digits) == 0 {
+		return nil, errors.New("empty list given to 'XFROMDIGITS'")
+	}
+
+	// Whether the integer is negative, which is only known once we see a nonzero digit.
+	sign := 0
+	result := 0
+
+	for _, element := range digits {
+		digit, ok := element.(Integer)
+		if !ok {
+			return nil, fmt.Errorf("invalid type given to 'XFROMDIGITS': %T", element)
+		}
+
+		if sign == 0 && digit != 0 {
+			sign = 1
+			if digit < 0 {
+				sign = -1
+			}
+		}
+
+		if digit*Integer(sign) < 0 || 9 < digit*Integer(sign) {
+			return nil, fmt.Errorf("invalid digit given to 'XFROMDIGITS': %d", digit)
+		}
+
+		if (0 < sign && (math.MaxInt-int(digit))/10 < result) ||
+			(sign < 0 && result < (math.MinInt-int(digit))/10) {
+			return nil, errors.New("result is too large for 'XFROMDIGITS'")
+		}
+
+		result = result*10 + int(digit)
+	}
+
+	return Integer(result), nil
+}
+
+// greatestCommonDivisor returns the greatest common divisor of lhs and rhs, which is never
+// negative, via the iterative Euclidean algorithm. The second return value is false if the result
+// is too large to fit in an int (which only happens when it's `-math.MinInt`).
+func greatestCommonDivisor(lhs, rhs int) (int, bool) {
+	for rhs != 0 {
+		lhs, rhs = rhs, lhs%rhs
+	}
+
+	if lhs == math.MinInt {
+		return 0, false
+	}
+
+	if lhs < 0 {
+		lhs = -lhs
+	}
+
+	return lhs, true
+}
+
+// gcd converts both its arguments to integers, and returns their greatest common divisor, which is
+// never negative. As a special case, the greatest common divisor of `0` and `0` is `0`.
+//
+// ## Examples
+//
+//	DUMP XGCD 12 18     #=> 6
+//	DUMP XGCD ~12 18    #=> 6
+//	DUMP XGCD 7 0       #=> 7
+//	DUMP XGCD 0 0       #=> 0
+//
+// ## Undefined Behaviour
+// Results which are too large yield an error:
+//
+//	DUMP XGCD (- ~9223372036854775807 1) 0   #!! error: result is too large
+func gcd(args []Value) (Value, error) {
+	lhs, err := executeToInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	rhs, err := executeToInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	divisor, ok := greatestCommonDivisor(lhs, rhs)
+	if !ok {
+		return nil, errors.New("result is too large for 'XGCD'")
+	}
+
+	return Integer(divisor), nil
+}
+
+// lcm converts both its arguments to integers, and returns their least common multiple, which is
+// never negative. As a special case, the least common multiple of `0` and anything is `0`.
+//
+// It's calculated as `|lhs / gcd(lhs, rhs) * rhs|`, which only overflows if the result itself is
+// too large to fit in an integer.
+//
+// ## Examples
+//
+//	DUMP XLCM 4 6       #=> 12
+//	DUMP XLCM ~4 6      #=> 12
+//	DUMP XLCM 5 0       #=> 0
+//
+// ## Undefined Behaviour
+// Unlike the arithmetic functions, which just wrap around, results which are too large yield an
+// error:
+//
+//	DUMP XLCM 9223372036854775807 2   #!! error: result is too large
+func lcm(args []Value) (Value, error) {
+	lhs, err := executeToInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	rhs, err := executeToInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if lhs == 0 || rhs == 0 {
+		return Integer(0), nil
+	}
+
+	divisor, ok := greatestCommonDivisor(lhs, rhs)
+	if !ok {
+		return nil, errors.New("result is too large for 'XLCM'")
+	}
+
+	quotient := lhs / divisor
+	multiple := quotient * rhs
+	if multiple/rhs != quotient || multiple == math.MinInt {
+		return nil, errors.New("result is too large for 'XLCM'")
+	}
+
+	if multiple < 0 {
+		multiple = -multiple
+	}
+
+	return Integer(multiple), nil
+}
+
+// prime converts its argument to an integer, and returns whether it's prime. Negative numbers, `0`,
+// and `1` are never prime.
+//
+// It uses trial division by `2` and odd numbers up to the square root of the argument, so it's
+// quick for small numbers, but can take a few seconds for large primes.
+//
+// ## Examples
+//
+//	DUMP XPRIME 2                     #=> true
+//	DUMP XPRIME 91                    #=> false
+//	DUMP XPRIME 1                     #=> false
+//	DUMP XPRIME ~7                    #=> false
+//	DUMP XPRIME 2147483647            #=> true
+func prime(args []Value) (Value, error) {
+	n, err := executeToInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 2 {
+		return Boolean(false), nil
+	}
+
+	if n%2 == 0 {
+		return Boolean(n == 2), nil
+	}
+
+	// Compare against `n / divisor` rather than squaring `divisor`, so it can't overflow.
+	for divisor := 3; divisor <= n/divisor; divisor += 2 {
+		if n%divisor == 0 {
+			return Boolean(false), nil
+		}
+	}
+
+	return Boolean(true), nil
+}
+
+// divides converts both its arguments to integers, and returns whether the first evenly divides the
+// second (ie whether the second is a multiple of the first).
+//
+// ## Examples
+//
+//	DUMP XDIVIDES 3 12                #=> true
+//	DUMP XDIVIDES 5 12                #=> false
+//	DUMP XDIVIDES ~4 12               #=> true
+//	DUMP XDIVIDES 7 0                 #=> true
+//
+// ## Undefined Behaviour
+// As with `%`, a zero divisor is an error:
+//
+//	DUMP XDIVIDES 0 12                #!! error: zero divisor given
+func divides(args []Value) (Value, error) {
+	divisor, err := executeToInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := executeToInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if divisor == 0 {
+		return nil, errors.New("zero divisor given to 'XDIVIDES'")
+	}
+
+	return Boolean(n%divisor == 0), nil
+}
+
+// even converts its argument to an integer, and returns whether it's even. Unlike `? 0 % x 2`, it
+// works the same for negative numbers, as it checks the lowest bit instead of using `%`.
+//
+// ## Examples
+//
+//	DUMP XEVEN 4       #=> true
+//	DUMP XEVEN 0       #=> true
+//	DUMP XEVEN ~3      #=> false
+//	DUMP XEVEN "12"    #=> true
+func even(args []Value) (Value, error) {
+	n, err := executeToInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return Boolean(n&1 == 0), nil
+}
+
+// odd converts its argument to an integer, and returns whether it's odd. Unlike `? 1 % x 2`, it
+// works the same for negative numbers, as it checks the lowest bit instead of using `%`.
+//
+// ## Examples
+//
+//	DUMP XODD 3        #=> true
+//	DUMP XODD ~3       #=> true
+//	DUMP XODD 0        #=> false
+func odd(args []Value) (Value, error) {
+	n, err := executeToInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return Boolean(n&1 == 1), nil
+}
+
+// clamp converts all three of its arguments to integers, and returns the first one bounded to be
+// between the second and third (inclusive). That is, values less than the second argument become
+// it, and values greater than the third become it.
+//
+// The integers are compared directly, rather than via `compare` (which `<` and `>` use), as it
+// subtracts them, which can overflow for very large and small integers.
+//
+// ## Examples
+//
+//	DUMP XCLAMP 5 1 10      #=> 5
+//	DUMP XCLAMP ~5 1 10     #=> 1
+//	DUMP XCLAMP 15 1 10     #=> 10
+//	DUMP XCLAMP 1 1 10      #=> 1
+//	DUMP XCLAMP 10 1 10     #=> 10
+//	DUMP XCLAMP 3 4 4       #=> 4
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XCLAMP` yield errors:
+//
+//	DUMP XCLAMP 5 10 1      #!! error: lower bound is greater than upper bound
+func clamp(args []Value) (Value, error) {
+	value, err := executeToInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	lower, err := executeToInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	upper, err := executeToInt(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	if upper < lower {
+		return nil, fmt.Errorf("lower bound is greater than upper bound for 'XCLAMP': %d > %d", lower, upper)
+	}
+
+	if value < lower {
+		return Integer(lower), nil
+	}
+
+	if upper < value {
+		return Integer(upper), nil
+	}
+
+	return Integer(value), nil
+}
+
+// sign converts its argument to an integer, and returns `-1`, `0`, or `1` depending on whether it's
+// negative, zero, or positive.
+//
+// ## Examples
+//
+//	DUMP XSIGN ~12                            #=> -1
+//	DUMP XSIGN 0                              #=> 0
+//	DUMP XSIGN 34                             #=> 1
+//	DUMP XSIGN (- ~9223372036854775807 1)     #=> -1
+func sign(args []Value) (Value, error) {
+	n, err := executeToInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case n < 0:
+		return Integer(-1), nil
+	case n > 0:
+		return Integer(1), nil
+	default:
+		return Integer(0), nil
+	}
+}
+
+// calculate converts its argument to a string, and then evaluates it as an arithmetic expression
+// written in the usual infix notation (eg `1 + 2 * 3`), returning the result.
+//
+// The supported grammar is:
+//
+//   - Integers, written as base-10 digits (eg `12`).
+//   - The binary operators `+`, `-`, `*`, and `/`. `*` and `/` have a higher precedence than `+` and
+//     `-`, and operators with the same precedence are evaluated left-to-right.
+//   - Unary `-` (negation) and `+` (which does nothing), which have the highest precedence.
+//   - Parentheses, for grouping.
+//   - Whitespace, which is ignored.
+//
+// Arithmetic works the same way as in Knight: Division rounds towards zero, and overflowing
+// operations wrap around. The expression is evaluated using the "shunting-yard" algorithm.
+//
+// ## Examples
+//
+//	DUMP XCALC "1 + 2 * 3"       #=> 7
+//	DUMP XCALC "(1 + 2) * 3"     #=> 9
+//	DUMP XCALC "10 - 4 - 3"      #=> 3
+//	DUMP XCALC "-7 / 2"          #=> -3
+//	DUMP XCALC "2 * -(1 + 1)"    #=> -4
+//
+// ## Undefined Behaviour
+// Malformed expressions, and division by zero, yield errors:
+//
+//	DUMP XCALC "1 +"             #!! error: unexpected end of expression
+//	DUMP XCALC "(1 + 2"          #!! error: unbalanced parentheses
+//	DUMP XCALC "1 / 0"           #!! error: zero divisor
+//	DUMP XCALC "a"               #!! error: unexpected character
+func calculate(args []Value) (Value, error) {
+	source, err := executeToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := evaluateInfix([]rune(source))
+	if err != nil {
+		return nil, fmt.Errorf("%v in 'XCALC'", err)
+	}
+
+	return Integer(result), nil
+}
+
+// infixPrecedence returns the precedence of an operator within evaluateInfix. Unary negation is
+// represented by `~`, as `-` is subtraction.
+func infixPrecedence(operator rune) int {
+	switch operator {
+	case '+', '-':
+		return 1
+	case '*', '/':
+		return 2
+	case '~':
+		return 3
+	default: // `(`, which is never popped by other operators.
+		return 0
+	}
+}
+
+// evaluateInfix is the helper function for calculate that evaluates an infix expression.
+func evaluateInfix(source []rune) (int, error) {
+	var operands []int
+	var operators []rune // both operators and `(`s that haven't been matched yet.
+
+	// apply pops operator's operands from `operands`, and pushes its result.
+	apply := func(operator rune) error {
+		if operator == '~' {
+			operands[len(operands)-1] = -operands[len(operands)-1]
+			return nil
+		}
+
+		lhs, rhs := operands[len(operands)-2], operands[len(operands)-1]
+		operands = operands[:len(operands)-2]
+
+		var result int
+		switch operator {
+		case '+':
+			result = lhs + rhs
+		case '-':
+			result = lhs - rhs
+		case '*':
+			result = lhs * rhs
+		case '/':
+			if rhs == 0 {
+				return errors.New("zero divisor")
+			}
+			result = lhs / rhs
+		}
+
+		operands = append(operands, result)
+		return nil
+	}
+
+	// Whether the next token should be an operand (or a unary operator or `(`), instead of a binary
+	// operator or `)`.
+	expectOperand := true
+
+	for i := 0; i < len(source); {
+		c := source[i]
+
+		switch {
+		case isWhitespace(c):
+			i++
+
+		case isDigit(c) && expectOperand:
+			start := i
+			for i < len(source) && isDigit(source[i]) {
+				i++
+			}
+
+			integer, err := strconv.Atoi(string(source[start:i]))
+			if err != nil {
+				return 0, fmt.Errorf("integer too large: %s", string(source[start:i]))
+			}
+
+			operands = append(operands, integer)
+			expectOperand = false
+
+		case c == '(' && expectOperand:
+			operators = append(operators, c)
+			i++
+
+		case c == '-' && expectOperand:
+			operators = append(operators, '~')
+			i++
+
+		case c == '+' && expectOperand:
+			i++ // unary `+` doesn't do anything
+
+		case c == ')' && !expectOperand:
+			// Apply everything since the matching `(`.
+			for len(operators) != 0 && operators[len(operators)-1] != '(' {
+				if err := apply(operators[len(operators)-1]); err != nil {
+					return 0, err
+				}
+				operators = operators[:len(operators)-1]
+			}
+
+			if len(operators) == 0 {
+				return 0, errors.New("unbalanced parentheses")
+			}
+
+			operators = operators[:len(operators)-1] // remove the `(`
+			i++
+
+		case (c == '+' || c == '-' || c == '*' || c == '/') && !expectOperand:
+			// Apply all the previous operators with at least the same precedence, as they come first.
+			for len(operators) != 0 &&
+				infixPrecedence(c) <= infixPrecedence(operators[len(operators)-1]) {
+				if err := apply(operators[len(operators)-1]); err != nil {
+					return 0, err
+				}
+				operators = operators[:len(operators)-1]
+			}
+
+			operators = append(operators, c)
+			expectOperand = true
+			i++
+
+		default:
+			return 0, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+
+	if expectOperand {
+		return 0, errors.New("unexpected end of expression")
+	}
+
+	for len(operators) != 0 {
+		operator := operators[len(operators)-1]
+		operators = operators[:len(operators)-1]
+
+		if operator == '(' {
+			return 0, errors.New("unbalanced parentheses")
+		}
+
+		if err := apply(operator); err != nil {
+			return 0, err
+		}
+	}
+
+	return operands[0], nil
+}
@@ -0,0 +1,140 @@
+package knight
+
+import "testing"
+
+func TestFibonacci(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{"XFIB 0", Integer(0)},
+		{"XFIB 1", Integer(1)},
+		{"XFIB 2", Integer(1)},
+		{"XFIB 3", Integer(2)},
+		{"XFIB 10", Integer(55)},
+		{"XFIB 92", Integer(7540113804746346429)},
+		{"XFIB 93", nil},
+		{"XFIB ~1", nil},
+	})
+}
+
+func TestCalculate(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{`XCALC "1 + 2 * 3"`, Integer(7)},
+		{`XCALC "2 * 3 + 1"`, Integer(7)},
+		{`XCALC "(1 + 2) * 3"`, Integer(9)},
+		{`XCALC "((4))"`, Integer(4)},
+		{`XCALC "10 - 4 - 3"`, Integer(3)},
+		{`XCALC "100 / 10 / 5"`, Integer(2)},
+		{`XCALC "-7 / 2"`, Integer(-3)},
+		{`XCALC "2 * -(1 + 1)"`, Integer(-4)},
+		{`XCALC "+5"`, Integer(5)},
+		{`XCALC "  12  "`, Integer(12)},
+
+		{`XCALC "1 / 0"`, nil},
+		{`XCALC "1 / (2 - 2)"`, nil},
+		{`XCALC "(1 + 2"`, nil},
+		{`XCALC "1 + 2)"`, nil},
+		{`XCALC "()"`, nil},
+		{`XCALC "1 +"`, nil},
+		{`XCALC ""`, nil},
+		{`XCALC "   "`, nil},
+		{`XCALC "a"`, nil},
+	})
+}
+
+func TestFromDigits(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{`XFROMDIGITS +,1 ,2`, Integer(12)},
+		{`XFROMDIGITS + @ 1234`, Integer(1234)},
+		{`XFROMDIGITS + @ ~1234`, Integer(-1234)},
+		{`XFROMDIGITS +,0 ,~7`, Integer(-7)},
+		{`XFROMDIGITS ,0`, Integer(0)},
+		{`XFROMDIGITS +,0 ,0`, Integer(0)},
+		{`XFROMDIGITS +,0 ,7`, Integer(7)},
+		{`XFROMDIGITS + @ 9223372036854775807`, Integer(9223372036854775807)},
+		{`XFROMDIGITS + @ (- ~9223372036854775807 1)`, Integer(-9223372036854775807 - 1)},
+
+		{`XFROMDIGITS @`, nil},
+		{`XFROMDIGITS ,10`, nil},
+		{`XFROMDIGITS ,~10`, nil},
+		{`XFROMDIGITS +,1 ,~2`, nil},
+		{`XFROMDIGITS +,~1 ,2`, nil},
+		{`XFROMDIGITS ,"1"`, nil},
+		{`XFROMDIGITS *,9 20`, nil},
+		{`XFROMDIGITS *,~9 20`, nil},
+	})
+}
+
+func TestPrimeAndDivides(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{`XPRIME 2`, Boolean(true)},
+		{`XPRIME 3`, Boolean(true)},
+		{`XPRIME 4`, Boolean(false)},
+		{`XPRIME 9`, Boolean(false)},
+		{`XPRIME 91`, Boolean(false)},
+		{`XPRIME 1`, Boolean(false)},
+		{`XPRIME 0`, Boolean(false)},
+		{`XPRIME ~2`, Boolean(false)},
+		{`XPRIME ~7`, Boolean(false)},
+		{`XPRIME 2147483647`, Boolean(true)},
+		{`XPRIME 2147483649`, Boolean(false)},
+
+		{`XDIVIDES 3 12`, Boolean(true)},
+		{`XDIVIDES 5 12`, Boolean(false)},
+		{`XDIVIDES ~4 12`, Boolean(true)},
+		{`XDIVIDES 4 ~12`, Boolean(true)},
+		{`XDIVIDES 1 7`, Boolean(true)},
+		{`XDIVIDES 7 0`, Boolean(true)},
+		{`XDIVIDES ~1 (- ~9223372036854775807 1)`, Boolean(true)},
+		{`XDIVIDES 0 12`, nil},
+		{`XDIVIDES 0 0`, nil},
+	})
+}
+
+func TestEvenAndOdd(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{`XEVEN 4`, Boolean(true)},
+		{`XEVEN 3`, Boolean(false)},
+		{`XEVEN 0`, Boolean(true)},
+		{`XEVEN ~4`, Boolean(true)},
+		{`XEVEN ~3`, Boolean(false)},
+		{`XEVEN "12"`, Boolean(true)},
+		{`XEVEN (- ~9223372036854775807 1)`, Boolean(true)},
+
+		{`XODD 3`, Boolean(true)},
+		{`XODD 4`, Boolean(false)},
+		{`XODD 0`, Boolean(false)},
+		{`XODD ~3`, Boolean(true)},
+		{`XODD ~4`, Boolean(false)},
+		{`XODD ~9223372036854775807`, Boolean(true)},
+	})
+}
+
+func TestClamp(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{`XCLAMP 5 1 10`, Integer(5)},
+		{`XCLAMP ~5 1 10`, Integer(1)},
+		{`XCLAMP 0 1 10`, Integer(1)},
+		{`XCLAMP 15 1 10`, Integer(10)},
+		{`XCLAMP 11 1 10`, Integer(10)},
+		{`XCLAMP 1 1 10`, Integer(1)},
+		{`XCLAMP 10 1 10`, Integer(10)},
+		{`XCLAMP 3 4 4`, Integer(4)},
+		{`XCLAMP 4 4 4`, Integer(4)},
+		{`XCLAMP ~5 ~10 ~1`, Integer(-5)},
+		{`XCLAMP 9223372036854775807 (- ~9223372036854775807 1) 0`, Integer(0)},
+		{`XCLAMP (- ~9223372036854775807 1) 0 9223372036854775807`, Integer(0)},
+		{`XCLAMP 5 10 1`, nil},
+	})
+}
+
+func TestSign(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{`XSIGN ~12`, Integer(-1)},
+		{`XSIGN ~1`, Integer(-1)},
+		{`XSIGN 0`, Integer(0)},
+		{`XSIGN 1`, Integer(1)},
+		{`XSIGN 34`, Integer(1)},
+		{`XSIGN "-5"`, Integer(-1)},
+		{`XSIGN 9223372036854775807`, Integer(1)},
+		{`XSIGN (- ~9223372036854775807 1)`, Integer(-1)},
+	})
+}
@@ -0,0 +1,573 @@
+package knight
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+	"unicode/utf8"
+)
+
+// Register the string extension functions. (See `init` in `function.go` for more details.)
+func init() {
+	ExtensionFunctions["XTOLF"] = &Function{name: "XTOLF", arity: 1, fn: toLF}
+	ExtensionFunctions["XTOCRLF"] = &Function{name: "XTOCRLF", arity: 1, fn: toCRLF}
+	ExtensionFunctions["XTOCR"] = &Function{name: "XTOCR", arity: 1, fn: toCR}
+	ExtensionFunctions["XDIFF"] = &Function{name: "XDIFF", arity: 2, fn: diff}
+	ExtensionFunctions["XPATCH"] = &Function{name: "XPATCH", arity: 2, fn: patch}
+	ExtensionFunctions["XSTARTSWITH"] = &Function{name: "XSTARTSWITH", arity: 2, fn: startsWith}
+	ExtensionFunctions["XENDSWITH"] = &Function{name: "XENDSWITH", arity: 2, fn: endsWith}
+	ExtensionFunctions["XREPLACE"] = &Function{name: "XREPLACE", arity: 3, fn: replace}
+	ExtensionFunctions["XPROGRESS"] = &Function{name: "XPROGRESS", arity: 3, fn: progress}
+	ExtensionFunctions["XBYTELENGTH"] = &Function{name: "XBYTELENGTH", arity: 1, fn: byteLength}
+	ExtensionFunctions["XCODEPOINTS"] = &Function{name: "XCODEPOINTS", arity: 1, fn: codepoints}
+	ExtensionFunctions["XFROMCODEPOINTS"] = &Function{name: "XFROMCODEPOINTS", arity: 1, fn: fromCodepoints}
+	ExtensionFunctions["XPADLEFT"] = &Function{name: "XPADLEFT", arity: 3, fn: padLeft}
+	ExtensionFunctions["XPADRIGHT"] = &Function{name: "XPADRIGHT", arity: 3, fn: padRight}
+}
+
+// convertLineEndings replaces every line ending in source with newline.
+//
+// Mixed line endings are normalized first: Every `\r\n` is turned into `\n` _before_ any lone `\r`s
+// are, so a `\r\n` is always treated as a single line ending, and never as two. Afterwards, each
+// `\n` is replaced with newline.
+func convertLineEndings(source, newline string) string {
+	normalized := strings.ReplaceAll(source, "\r\n", "\n")
+	normalized = strings.ReplaceAll(normalized, "\r", "\n")
+
+	return strings.ReplaceAll(normalized, "\n", newline)
+}
+
+// toLF converts its argument to a string, and then replaces all of its line endings (`\r\n`, `\n`,
+// and `\r`) with `\n`.
+//
+// ## Examples
+//
+//	DUMP XTOLF "a␍␤b␍c␤"   #=> "a\nb\nc\n"
+//	DUMP XTOLF "a␍␍␤b"     #=> "a\n\nb"     (the `\r\n` is collapsed before the lone `\r`)
+func toLF(args []Value) (Value, error) {
+	source, err := executeToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return String(convertLineEndings(source, "\n")), nil
+}
+
+// toCRLF converts its argument to a string, and then replaces all of its line endings (`\r\n`, `\n`,
+// and `\r`) with `\r\n`.
+//
+// ## Examples
+//
+//	DUMP XTOCRLF "a␍␤b␍c␤" #=> "a\r\nb\r\nc\r\n"
+func toCRLF(args []Value) (Value, error) {
+	source, err := executeToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return String(convertLineEndings(source, "\r\n")), nil
+}
+
+// toCR converts its argument to a string, and then replaces all of its line endings (`\r\n`, `\n`,
+// and `\r`) with `\r`.
+//
+// ## Examples
+//
+//	DUMP XTOCR "a␍␤b␍c␤"   #=> "a\rb\rc\r"
+func toCR(args []Value) (Value, error) {
+	source, err := executeToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return String(convertLineEndings(source, "\r")), nil
+}
+
+// diff converts both its arguments to strings, and then returns a line-based diff between them.
+//
+// Lines are separated by `\n` (so a trailing newline results in a final empty line). The diff is
+// a list of change records, each of which is a two-element list of an operation and a line:
+//
+//   - `[" ", line]` means `line` is in both strings.
+//   - `["-", line]` means `line` is only in the first string.
+//   - `["+", line]` means `line` is only in the second string.
+//
+// The records are ordered such that reading the `" "` and `"-"` lines yields the first string, and
+// reading the `" "` and `"+"` lines yields the second. When a line is changed, its `"-"` record
+// comes before its `"+"` record. The diff is computed by finding the longest common subsequence of
+// the two strings' lines, which takes time and memory proportional to the product of their lengths.
+//
+// ## Examples
+//
+//	DUMP XDIFF "a" "a"                 #=> [[" ", "a"]]
+//	DUMP XDIFF "a␤b␤c" "a␤c␤d"         #=> [[" ", "a"], ["-", "b"], [" ", "c"], ["+", "d"]]
+//	DUMP XDIFF "a" "b"                 #=> [["-", "a"], ["+", "b"]]
+func diff(args []Value) (Value, error) {
+	before, err := executeToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := executeToString(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	beforeLines := strings.Split(before, "\n")
+	afterLines := strings.Split(after, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of `beforeLines[i:]` and `afterLines[j:]`.
+	lcs := make([][]int, len(beforeLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(afterLines)+1)
+	}
+
+	for i := len(beforeLines) - 1; i >= 0; i-- {
+		for j := len(afterLines) - 1; j >= 0; j-- {
+			if beforeLines[i] == afterLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// Walk the table from the start, emitting a record for each line we pass over.
+	var records List
+	i, j := 0, 0
+	for i < len(beforeLines) || j < len(afterLines) {
+		switch {
+		case i < len(beforeLines) && j < len(afterLines) && beforeLines[i] == afterLines[j]:
+			records = append(records, List{String(" "), String(beforeLines[i])})
+			i++
+			j++
+
+		case j == len(afterLines) || (i < len(beforeLines) && lcs[i+1][j] >= lcs[i][j+1]):
+			records = append(records, List{String("-"), String(beforeLines[i])})
+			i++
+
+		default:
+			records = append(records, List{String("+"), String(afterLines[j])})
+			j++
+		}
+	}
+
+	return records, nil
+}
+
+// patch converts its first argument to a string, and then applies the diff given as its second
+// argument to it, returning the result. The diff must be in the same format that `XDIFF` returns,
+// so `XPATCH a (XDIFF a b)` always yields `b`.
+//
+// The records are applied in order, starting at the first line of the string: `[" ", line]` keeps
+// the next line, `["-", line]` removes it, and `["+", line]` inserts `line`. An error is returned if
+// a `" "` or `"-"` record's line doesn't match the next line, or if any lines are left over after
+// all the records are applied.
+//
+// ## Examples
+//
+//	DUMP XPATCH "a␤b" ++ ,+ ," " "a" ,+ ,"-" "b" ,+ ,"+" "c"  #=> "a\nc"
+//	DUMP XPATCH "a" ,+ ,"-" "b"                              #!! error: line mismatch
+//
+// ## Undefined Behaviour
+// Malformed records (ie ones which aren't two-element lists, or whose operation isn't `" "`, `"-"`,
+// or `"+"`) result in errors:
+//
+//	DUMP XPATCH "a" ,,"a"                                    #!! error: invalid record
+func patch(args []Value) (Value, error) {
+	base, err := executeToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := executeToSlice(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	baseLines := strings.Split(base, "\n")
+	var patchedLines []string
+	next := 0 // the index of the next line in `baseLines` to look at.
+
+	for _, element := range records {
+		record, ok := element.(List)
+		if !ok || len(record) != 2 {
+			return nil, fmt.Errorf("invalid record given to 'XPATCH': %s", dumpToString(element))
+		}
+
+		operation, err := record[0].ToString()
+		if err != nil {
+			return nil, err
+		}
+
+		line, err := record[1].ToString()
+		if err != nil {
+			return nil, err
+		}
+
+		// Insertions don't need to look at the base string.
+		if operation == "+" {
+			patchedLines = append(patchedLines, line)
+			continue
+		}
+
+		if operation != " " && operation != "-" {
+			return nil, fmt.Errorf("invalid operation given to 'XPATCH': %q", operation)
+		}
+
+		if len(baseLines) <= next || baseLines[next] != line {
+			return nil, fmt.Errorf("line %d doesn't match %q in 'XPATCH'", next+1, line)
+		}
+
+		if operation == " " {
+			patchedLines = append(patchedLines, line)
+		}
+		next++
+	}
+
+	if next != len(baseLines) {
+		return nil, errors.New("not all lines were covered by 'XPATCH'")
+	}
+
+	return String(strings.Join(patchedLines, "\n")), nil
+}
+
+// startsWith converts both its arguments to strings, and returns whether the first starts with the
+// second. Every string starts with the empty string.
+//
+// ## Examples
+//
+//	DUMP XSTARTSWITH "hello" "he"  #=> true
+//	DUMP XSTARTSWITH "hello" "lo"  #=> false
+//	DUMP XSTARTSWITH "hello" ""    #=> true
+//	DUMP XSTARTSWITH 123 1         #=> true
+func startsWith(args []Value) (Value, error) {
+	str, err := executeToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, err := executeToString(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	// Since both strings are valid UTF-8, comparing their bytes is the same as comparing their runes.
+	return Boolean(strings.HasPrefix(str, prefix)), nil
+}
+
+// endsWith converts both its arguments to strings, and returns whether the first ends with the
+// second. Every string ends with the empty string.
+//
+// ## Examples
+//
+//	DUMP XENDSWITH "hello" "lo"    #=> true
+//	DUMP XENDSWITH "hello" "he"    #=> false
+//	DUMP XENDSWITH "hello" ""      #=> true
+//	DUMP XENDSWITH "😁" "😁"       #=> true
+func endsWith(args []Value) (Value, error) {
+	str, err := executeToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	suffix, err := executeToString(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	// See startsWith for why comparing bytes is fine.
+	return Boolean(strings.HasSuffix(str, suffix)), nil
+}
+
+// replace converts all three of its arguments to strings, and returns the first with every
+// occurrence of the second replaced by the third. Occurrences are found from left to right, and
+// don't overlap.
+//
+// If the string to search for is empty, the replacement is inserted at the start of the string and
+// after every rune (including at the end).
+//
+// ## Examples
+//
+//	DUMP XREPLACE "hello" "l" "L"   #=> "heLLo"
+//	DUMP XREPLACE "aaaa" "aa" "b"   #=> "bb"
+//	DUMP XREPLACE "hello" "x" "y"   #=> "hello"
+//	DUMP XREPLACE "αβ" "" "-"       #=> "-α-β-"
+func replace(args []Value) (Value, error) {
+	str, err := executeToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	search, err := executeToString(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	replacement, err := executeToString(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	return String(strings.ReplaceAll(str, search, replacement)), nil
+}
+
+// progress converts all three of its arguments to integers, and returns a progress bar showing how
+// far the first argument is towards the second, with a width of the third.
+//
+// The bar is the width's amount of characters in brackets, each of which is `#` for done or `-` for
+// not done yet, followed by a space and the percentage done. Partially-done characters are shown as
+// not done, and the percentage is rounded down. Values below zero and above the total are treated
+// as zero and the total, respectively.
+//
+// ## Examples
+//
+//	OUTPUT XPROGRESS 0 10 8      #=> [--------] 0%
+//	OUTPUT XPROGRESS 5 10 8      #=> [####----] 50%
+//	OUTPUT XPROGRESS 10 10 8     #=> [########] 100%
+//	OUTPUT XPROGRESS 1 3 4       #=> [#---] 33%
+//	OUTPUT XPROGRESS 20 10 0     #=> [] 100%
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XPROGRESS` yield errors:
+//
+//	OUTPUT XPROGRESS 1 0 10      #!! error: non-positive total
+//	OUTPUT XPROGRESS 1 10 ~1     #!! error: negative width
+//
+// Widths larger than a gibibyte (see maxPaddingLength) also yield an error:
+//
+//	OUTPUT XPROGRESS 1 2 9223372036854775807  #!! error: width is too large
+func progress(args []Value) (Value, error) {
+	current, err := executeToInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := executeToInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("non-positive total given to 'XPROGRESS': %d", total)
+	}
+
+	width, err := executeToInt(args[2])
+	if err != nil {
+		return nil, err
+	}
+	if width < 0 {
+		return nil, fmt.Errorf("negative width given to 'XPROGRESS': %d", width)
+	}
+	if maxPaddingLength < width {
+		return nil, fmt.Errorf("width given to 'XPROGRESS' is too large: %d", width)
+	}
+
+	if current < 0 {
+		current = 0
+	} else if total < current {
+		current = total
+	}
+
+	done := scaleDown(current, width, total)
+	bar := strings.Repeat("#", done) + strings.Repeat("-", width-done)
+
+	return String(fmt.Sprintf("[%s] %d%%", bar, scaleDown(current, 100, total))), nil
+}
+
+// scaleDown returns `n * scale / total`, rounded down, without overflowing. All three must be
+// nonnegative, n must be at most total, and total must be positive, so the result is at most scale.
+func scaleDown(n, scale, total int) int {
+	// The product is computed as 128 bits, which can't overflow. The quotient fits in 64 bits (as
+	// it's at most scale), so the product's high half is always less than total, as Div64 requires.
+	hi, lo := bits.Mul64(uint64(n), uint64(scale))
+	quotient, _ := bits.Div64(hi, lo, uint64(total))
+	return int(quotient)
+}
+
+// byteLength converts its argument to a string, and returns its length in bytes when encoded as
+// UTF-8. This differs from `LENGTH`, which returns the amount of runes (unicode codepoints) in a
+// string, whenever the string has characters outside of ASCII.
+//
+// ## Examples
+//
+//	DUMP XBYTELENGTH "hello"  #=> 5
+//	DUMP XBYTELENGTH "😁"     #=> 4  (whereas `LENGTH "😁"` is 1)
+//	DUMP XBYTELENGTH 123      #=> 3
+//
+// ## Undefined Behaviour
+// Types which can't be converted to strings yield an error:
+//
+//	DUMP XBYTELENGTH BLOCK a  #!! error: cant convert to a string
+func byteLength(args []Value) (Value, error) {
+	str, err := executeToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return Integer(len(str)), nil
+}
+
+// codepoints converts its argument to a string, and returns a list of the codepoints of its runes.
+// It's the bulk version of `ASCII` for strings.
+//
+// ## Examples
+//
+//	DUMP XCODEPOINTS "Hi"      #=> [72, 105]
+//	DUMP XCODEPOINTS "a😁"     #=> [97, 128513]
+//	DUMP XCODEPOINTS ""        #=> []
+//
+// ## Undefined Behaviour
+// Types which can't be converted to strings yield an error:
+//
+//	DUMP XCODEPOINTS BLOCK a   #!! error: cant convert to a string
+func codepoints(args []Value) (Value, error) {
+	str, err := executeToString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	list := List{}
+	for _, r := range str {
+		list = append(list, Integer(r))
+	}
+
+	return list, nil
+}
+
+// fromCodepoints converts its argument to a list, and returns the string whose runes have the
+// codepoints in the list. It's the bulk version of `ASCII` for integers, and the inverse of
+// `XCODEPOINTS`.
+//
+// ## Examples
+//
+//	DUMP XFROMCODEPOINTS +,72 ,105        #=> "Hi"
+//	DUMP XFROMCODEPOINTS +,97 ,128513     #=> "a😁"
+//	DUMP XFROMCODEPOINTS @                #=> ""
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XFROMCODEPOINTS` yield errors:
+//
+//	DUMP XFROMCODEPOINTS ,"a"             #!! error: invalid type (elements must be integers)
+//	DUMP XFROMCODEPOINTS ,~1              #!! error: invalid codepoint
+//	DUMP XFROMCODEPOINTS ,55296           #!! error: invalid codepoint (surrogates aren't runes)
+func fromCodepoints(args []Value) (Value, error) {
+	list, err := executeToSlice(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var builder strings.Builder
+	for _, element := range list {
+		codepoint, ok := element.(Integer)
+		if !ok {
+			return nil, fmt.Errorf("invalid type given to 'XFROMCODEPOINTS': %T", element)
+		}
+
+		// (We check that converting to a rune doesn't change the value, as `rune` is only 32 bits.)
+		if Integer(rune(codepoint)) != codepoint || !utf8.ValidRune(rune(codepoint)) {
+			return nil, fmt.Errorf("invalid codepoint given to 'XFROMCODEPOINTS': %d", codepoint)
+		}
+
+		builder.WriteRune(rune(codepoint))
+	}
+
+	return String(builder.String()), nil
+}
+
+// maxPaddingLength is the most bytes of padding that padLeft and padRight will add. Without it, huge
+// widths would make them try to allocate more memory than exists, which crashes the entire process
+// (and can't be recovered from).
+const maxPaddingLength = 1 << 30
+
+// padding is a helper function for padLeft and padRight. It converts args to a string, width, and
+// fill character, and returns the string along with the padding needed to make it at least width
+// runes long. An error is returned if the fill isn't exactly one rune, or if the padding would be
+// longer than maxPaddingLength. The functionName argument is just used for error messages.
+func padding(args []Value, functionName string) (string, string, error) {
+	str, err := executeToString(args[0])
+	if err != nil {
+		return "", "", err
+	}
+
+	width, err := executeToInt(args[1])
+	if err != nil {
+		return "", "", err
+	}
+
+	fill, err := executeToString(args[2])
+	if err != nil {
+		return "", "", err
+	}
+
+	if utf8.RuneCountInString(fill) != 1 {
+		return "", "", fmt.Errorf("fill given to '%s' isn't one character: %q", functionName, fill)
+	}
+
+	missing := width - utf8.RuneCountInString(str)
+	if missing <= 0 {
+		return str, "", nil
+	}
+
+	if missing > maxPaddingLength/len(fill) {
+		return "", "", fmt.Errorf("width given to '%s' is too large: %d", functionName, width)
+	}
+
+	return str, strings.Repeat(fill, missing), nil
+}
+
+// padLeft converts its arguments to a string, an integer width, and a string fill character, and
+// then returns the string with enough copies of the fill added to its start to make it width runes
+// long. Strings which are already at least width runes long are returned unchanged.
+//
+// ## Examples
+//
+//	DUMP XPADLEFT "7" 3 "0"        #=> "007"
+//	DUMP XPADLEFT 12 5 " "         #=> "   12"
+//	DUMP XPADLEFT "😁" 3 "·"       #=> "··😁"
+//	DUMP XPADLEFT "hello" 2 "-"    #=> "hello"
+//
+// ## Undefined Behaviour
+// Fills which aren't exactly one rune yield an error:
+//
+//	DUMP XPADLEFT "a" 3 "ab"       #!! error: fill isn't one character
+//	DUMP XPADLEFT "a" 3 ""         #!! error: fill isn't one character
+//
+// Widths that would need more than a gibibyte of padding yield an error:
+//
+//	DUMP XPADLEFT "a" 9223372036854775807 "x"  #!! error: width is too large
+func padLeft(args []Value) (Value, error) {
+	str, fill, err := padding(args, "XPADLEFT")
+	if err != nil {
+		return nil, err
+	}
+
+	return String(fill + str), nil
+}
+
+// padRight is like padLeft, except the fill is added to the end of the string.
+//
+// ## Examples
+//
+//	DUMP XPADRIGHT "ab" 4 "."      #=> "ab.."
+//	DUMP XPADRIGHT "😁" 3 "!"      #=> "😁!!"
+//	DUMP XPADRIGHT "hello" 2 "-"   #=> "hello"
+//
+// ## Undefined Behaviour
+// Fills which aren't exactly one rune yield an error:
+//
+//	DUMP XPADRIGHT "a" 3 "ab"      #!! error: fill isn't one character
+//
+// Widths that would need more than a gibibyte of padding yield an error:
+//
+//	DUMP XPADRIGHT "a" 9223372036854775807 "x" #!! error: width is too large
+func padRight(args []Value) (Value, error) {
+	str, fill, err := padding(args, "XPADRIGHT")
+	if err != nil {
+		return nil, err
+	}
+
+	return String(str + fill), nil
+}
@@ -0,0 +1,212 @@
+package knight
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLineEndingConversions(t *testing.T) {
+	const mixed = "a\r\nb\nc\rd\r\r\ne\n\r"
+
+	for _, test := range []struct {
+		name     string
+		fn       func([]Value) (Value, error)
+		expected string
+	}{
+		{"XTOLF", toLF, "a\nb\nc\nd\n\ne\n\n"},
+		{"XTOCRLF", toCRLF, "a\r\nb\r\nc\r\nd\r\n\r\ne\r\n\r\n"},
+		{"XTOCR", toCR, "a\rb\rc\rd\r\re\r\r"},
+	} {
+		result, err := test.fn([]Value{String(mixed)})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		if result != String(test.expected) {
+			t.Errorf("%s: expected %q, got %#v", test.name, test.expected, result)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	for _, test := range []struct {
+		before, after string
+		expected      List
+	}{
+		{"a\nb", "a\nb", List{
+			List{String(" "), String("a")},
+			List{String(" "), String("b")},
+		}},
+		{"a\nc", "a\nb\nc\nd", List{
+			List{String(" "), String("a")},
+			List{String("+"), String("b")},
+			List{String(" "), String("c")},
+			List{String("+"), String("d")},
+		}},
+		{"a\nb\nc\nd", "b\nd", List{
+			List{String("-"), String("a")},
+			List{String(" "), String("b")},
+			List{String("-"), String("c")},
+			List{String(" "), String("d")},
+		}},
+		{"a\nb\nc", "a\nx\nc", List{
+			List{String(" "), String("a")},
+			List{String("-"), String("b")},
+			List{String("+"), String("x")},
+			List{String(" "), String("c")},
+		}},
+		{"", "a", List{
+			List{String("-"), String("")},
+			List{String("+"), String("a")},
+		}},
+	} {
+		result, err := diff([]Value{String(test.before), String(test.after)})
+		if err != nil {
+			t.Errorf("%q %q: unexpected error: %v", test.before, test.after, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("%q %q: expected %s, got %s",
+				test.before, test.after, dumpToString(test.expected), dumpToString(result))
+		}
+	}
+}
+
+func TestPatchRoundTrip(t *testing.T) {
+	for _, test := range []struct{ before, after string }{
+		{"a\nb\nc", "a\nc\nd"},
+		{"one\ntwo\n", "zero\none\ntwo\nthree\n"},
+		{"héllo\nwörld", "héllo\n世界\nwörld"},
+		{"", ""},
+		{"", "a\nb"},
+		{"a\nb", ""},
+	} {
+		records, err := diff([]Value{String(test.before), String(test.after)})
+		if err != nil {
+			t.Errorf("%q %q: unexpected error from XDIFF: %v", test.before, test.after, err)
+			continue
+		}
+
+		result, err := patch([]Value{String(test.before), records})
+		if err != nil {
+			t.Errorf("%q %q: unexpected error from XPATCH: %v", test.before, test.after, err)
+			continue
+		}
+
+		if result != String(test.after) {
+			t.Errorf("%q %q: patching yielded %#v", test.before, test.after, result)
+		}
+	}
+}
+
+func TestPatchMismatch(t *testing.T) {
+	records := List{List{String("-"), String("b")}}
+
+	if _, err := patch([]Value{String("a"), records}); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestPaddingTooLarge(t *testing.T) {
+	for _, width := range []int{maxPaddingLength + 2, 1 << 50, math.MaxInt} {
+		for _, fn := range []func([]Value) (Value, error){padLeft, padRight} {
+			if _, err := fn([]Value{String("a"), Integer(width), String("x")}); err == nil {
+				t.Errorf("expected an error for a width of %d", width)
+			}
+		}
+	}
+
+	// Multi-byte fills count every byte towards the limit.
+	if _, err := padLeft([]Value{String(""), Integer(maxPaddingLength), String("·")}); err == nil {
+		t.Error("expected an error for a multi-byte fill")
+	}
+}
+
+func TestProgress(t *testing.T) {
+	for _, test := range []struct {
+		current, total, width int
+		expected              String
+	}{
+		{0, 10, 8, "[--------] 0%"},
+		{5, 10, 8, "[####----] 50%"},
+		{1, 3, 4, "[#---] 33%"},
+		{20, 10, 0, "[] 100%"},
+		{-5, 10, 2, "[--] 0%"},
+
+		// The products of these overflow, but the results don't.
+		{math.MaxInt / 2, math.MaxInt, 4, "[#---] 49%"},
+		{math.MaxInt/2 + 1, math.MaxInt, 4, "[##--] 50%"},
+		{math.MaxInt - 1, math.MaxInt, 1000, "[" + String(strings.Repeat("#", 999)) + "-] 99%"},
+	} {
+		result, err := progress([]Value{Integer(test.current), Integer(test.total), Integer(test.width)})
+		if err != nil {
+			t.Errorf("%d %d %d: unexpected error: %v", test.current, test.total, test.width, err)
+			continue
+		}
+
+		if result != test.expected {
+			t.Errorf("%d %d %d: expected %q, got %#v", test.current, test.total, test.width, test.expected, result)
+		}
+	}
+}
+
+func TestProgressErrors(t *testing.T) {
+	for _, test := range [][3]int{
+		{1, 0, 10},
+		{1, 10, -1},
+		{1, 2, maxPaddingLength + 1},
+		{1, 2, math.MaxInt},
+	} {
+		if _, err := progress([]Value{Integer(test[0]), Integer(test[1]), Integer(test[2])}); err == nil {
+			t.Errorf("%v: expected an error", test)
+		}
+	}
+}
+
+func TestStartsWithAndEndsWith(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{`XSTARTSWITH "hello" "he"`, Boolean(true)},
+		{`XSTARTSWITH "hello" "lo"`, Boolean(false)},
+		{`XSTARTSWITH "hello" ""`, Boolean(true)},
+		{`XSTARTSWITH "" ""`, Boolean(true)},
+		{`XSTARTSWITH "" "a"`, Boolean(false)},
+		{`XSTARTSWITH "he" "hello"`, Boolean(false)},
+		{`XSTARTSWITH "😁x" "😁"`, Boolean(true)},
+		{`XSTARTSWITH 123 1`, Boolean(true)},
+
+		{`XENDSWITH "hello" "lo"`, Boolean(true)},
+		{`XENDSWITH "hello" "he"`, Boolean(false)},
+		{`XENDSWITH "hello" ""`, Boolean(true)},
+		{`XENDSWITH "" ""`, Boolean(true)},
+		{`XENDSWITH "" "a"`, Boolean(false)},
+		{`XENDSWITH "lo" "hello"`, Boolean(false)},
+		{`XENDSWITH "x😁" "😁"`, Boolean(true)},
+		{`XENDSWITH 123 3`, Boolean(true)},
+	})
+}
+
+func TestCodepoints(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{`XCODEPOINTS "Hi"`, List{Integer(72), Integer(105)}},
+		{`XCODEPOINTS "a😁"`, List{Integer(97), Integer(128513)}},
+		{`XCODEPOINTS ""`, List{}},
+		{`XCODEPOINTS BLOCK a`, nil},
+
+		{`XFROMCODEPOINTS +,72 ,105`, String("Hi")},
+		{`XFROMCODEPOINTS +,97 ,128513`, String("a😁")},
+		{`XFROMCODEPOINTS @`, String("")},
+		{`XFROMCODEPOINTS XCODEPOINTS "a😁é"`, String("a😁é")},
+		{`XFROMCODEPOINTS ,1114111`, String("\U0010FFFF")},
+
+		{`XFROMCODEPOINTS ,"a"`, nil},
+		{`XFROMCODEPOINTS ,~1`, nil},
+		{`XFROMCODEPOINTS ,55296`, nil},
+		{`XFROMCODEPOINTS ,57343`, nil},
+		{`XFROMCODEPOINTS ,1114112`, nil},
+		{`XFROMCODEPOINTS ,4294967393`, nil},
+	})
+}
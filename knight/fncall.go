@@ -3,6 +3,9 @@ package knight
 import (
 	"errors"
 	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
 )
 
 // FnCall represents a function call (eg `+ 1 2`) in Knight. It implements Value, but
@@ -11,11 +14,47 @@ import (
 type FnCall struct {
 	function  *Function
 	arguments []Value
+	position  *Position // where the function call was parsed, or nil if RecordPositions was false.
+	timestamp time.Time // used by `XTHROTTLE` and `XDEBOUNCE`; see timestampMutex.
 }
 
 // Compile-time assertion that FnCall implements the Value interface.
 var _ Value = &FnCall{}
 
+// MaxCallDepth is the maximum amount of function calls that can be nested within each other (such
+// as via recursive `CALL`s) before RecursionLimitExceeded is returned. Without it, deeply recursive
+// programs would overflow the go stack, which crashes the entire process (and can't be recovered
+// from). Setting it to zero removes the limit. (Calls in "tail position" don't count towards it;
+// see FnCall.Execute.)
+var MaxCallDepth = 100000
+
+// RecursionLimitExceeded is returned when function calls are nested more than MaxCallDepth deep.
+var RecursionLimitExceeded = errors.New("maximum recursion depth exceeded")
+
+// callDepth is the amount of function calls that are currently being executed. It's atomic so that
+// programs running on separate goroutines (see ConcurrentVariables) don't race on it, although they
+// then share the same limit.
+var callDepth atomic.Int64
+
+// RuntimeError is returned when a function call that has a position (see RecordPositions) fails.
+// Only the innermost function call that failed wraps the error, so the position is where the
+// error actually happened.
+type RuntimeError struct {
+	Err      error    // The error that occurred.
+	Function string   // The name of the function whose call failed.
+	Position Position // Where the function call is in the source code.
+}
+
+// Error returns the underlying error's message, prefixed by the line it happened on.
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("[line %d] %v", e.Position.Line, e.Err)
+}
+
+// Unwrap returns the underlying error, so that eg `errors.Is(err, TimeLimitExceeded)` works.
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
 // NewFnCall constructs a new FnCall. It'll panic if the amount of arguments given isn't
 // equal to the arity of the function.
 func NewFnCall(function *Function, arguments []Value) *FnCall {
@@ -27,21 +66,87 @@ func NewFnCall(function *Function, arguments []Value) *FnCall {
 	return &FnCall{function: function, arguments: arguments}
 }
 
-// Execute executes the function call by passing its arguments to its function.
-func (a *FnCall) Execute() (Value, error) {
-	return (a.function.fn)(a.arguments)
+// Execute executes the function call by passing its arguments to its function. If an `XTIMEOUT`'s
+// time limit has passed, TimeLimitExceeded is returned instead.
+//
+// If the function has a `tail` version, it's used instead, and whatever it returns is then
+// executed. When that's another function call, it's executed by this same loop (instead of calling
+// its Execute method), so that deeply-recursive Knight programs don't overflow the go stack as long
+// as their recursion is in "tail position" (such as at the end of a `;` or in an `IF` branch).
+//
+// If executing this would nest function calls more than MaxCallDepth deep, RecursionLimitExceeded
+// is returned instead.
+//
+// If the function call has a position (see RecordPositions), errors are wrapped in a RuntimeError,
+// unless they already are one.
+func (a *FnCall) Execute() (result Value, err error) {
+	// This uses whatever `a` is when the error is returned, which is the call that failed. As the
+	// loop below replaces `a` with tail calls, that might not be the call we started with, and it
+	// might not have a position (eg if it was created via NewFnCall), so it's checked here.
+	defer func() {
+		if err == nil || a.position == nil {
+			return
+		}
+
+		var runtimeError *RuntimeError
+		if !errors.As(err, &runtimeError) {
+			err = &RuntimeError{Err: err, Function: a.function.name, Position: *a.position}
+		}
+	}()
+
+	if depth := callDepth.Add(1); MaxCallDepth != 0 && int64(MaxCallDepth) < depth {
+		callDepth.Add(-1)
+		return nil, RecursionLimitExceeded
+	}
+	defer callDepth.Add(-1)
+
+	for {
+		if err := checkDeadline(); err != nil {
+			return nil, err
+		}
+
+		if a.function.call != nil {
+			return (a.function.call)(a)
+		}
+
+		if a.function.tail == nil {
+			return (a.function.fn)(a.arguments)
+		}
+
+		next, err := (a.function.tail)(a.arguments)
+		if err != nil {
+			return nil, err
+		}
+
+		fnCall, ok := next.(*FnCall)
+		if !ok {
+			return next.Execute()
+		}
+
+		a = fnCall
+	}
+}
+
+// Position returns where the function call was parsed. The second return value is false if the
+// position wasn't recorded (see RecordPositions).
+func (a *FnCall) Position() (Position, bool) {
+	if a.position == nil {
+		return Position{}, false
+	}
+
+	return *a.position, true
 }
 
-// Dump writes a debugging representation of the function call to stdout.
-func (a *FnCall) Dump() {
-	fmt.Printf("FnCall(%c", a.function.name)
+// Dump writes a debugging representation of the function call to w.
+func (a *FnCall) Dump(w io.Writer) {
+	fmt.Fprintf(w, "FnCall(%s", a.function.name)
 
 	for _, arg := range a.arguments {
-		fmt.Print(", ")
-		arg.Dump()
+		fmt.Fprint(w, ", ")
+		arg.Dump(w)
 	}
 
-	fmt.Print(")")
+	fmt.Fprint(w, ")")
 }
 
 // Conversions: They always return errors, as function calls cannot be converted to other types.
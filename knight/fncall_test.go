@@ -0,0 +1,22 @@
+package knight
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRuntimeErrorFromTailCallWithoutPosition(t *testing.T) {
+	RecordPositions = true
+	defer func() { RecordPositions = false }()
+
+	// The `;` that XCOMPOSE creates has no position, and is the call that fails.
+	_, err := Evaluate("; = f XCOMPOSE (BLOCK 1) (BLOCK undefinedvar) : CALL f")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	var runtimeError *RuntimeError
+	if !errors.As(err, &runtimeError) {
+		t.Fatalf("expected a RuntimeError, got %v", err)
+	}
+}
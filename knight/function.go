@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"errors" // For those non-gophers, `errors.New` is `fmt.Errorf` when no interpolation is needed.
 	"fmt"
+	"io"
 	"math"
 	"math/rand"
 	"os"
@@ -26,7 +27,23 @@ type Function struct {
 	arity int
 
 	// The go function associated with this function.
+	//
+	// The arguments it's given are the FnCall's own argument slice, which is created once when the
+	// function call is parsed and then reused every time it's executed, so calls don't allocate.
+	// As such, functions must never modify their arguments slice, nor keep it (or a sublist of it)
+	// around after they return.
 	fn func([]Value) (Value, error)
+
+	// An optional alternative to `fn`, used by functions which need the function call itself rather
+	// than just its arguments (such as `XTHROTTLE`, which keeps track of when each call last
+	// executed its body).
+	call func(*FnCall) (Value, error)
+
+	// An optional alternative to `fn`, used by functions that end by executing one of their
+	// arguments (such as `;` or `IF`). Instead of executing that argument, `tail` returns it
+	// unexecuted, and `FnCall.Execute` executes it in a loop. This means that "tail calls" (eg a
+	// recursive `CALL` at the end of an `IF`) don't use up any more of the go stack.
+	tail func([]Value) (Value, error)
 }
 
 var (
@@ -44,7 +61,7 @@ var (
 		// Arity 1
 		':': &Function{name: ":", arity: 1, fn: noop},
 		'B': &Function{name: "BLOCK", arity: 1, fn: block},
-		'C': &Function{name: "CALL", arity: 1, fn: call},
+		'C': &Function{name: "CALL", arity: 1, fn: call, tail: callTail},
 		'Q': &Function{name: "QUIT", arity: 1, fn: quit},
 		'!': &Function{name: "!", arity: 1, fn: not},
 		'L': &Function{name: "LENGTH", arity: 1, fn: length},
@@ -68,22 +85,172 @@ var (
 		'?': &Function{name: "?", arity: 2, fn: equalTo},
 		'&': &Function{name: "&", arity: 2, fn: and},
 		'|': &Function{name: "|", arity: 2, fn: or},
-		';': &Function{name: ";", arity: 2, fn: then},
+		';': &Function{name: ";", arity: 2, fn: then, tail: thenTail},
 		'=': &Function{name: "=", arity: 2, fn: assign},
 		'W': &Function{name: "WHILE", arity: 2, fn: while},
 
 		// Arity 3
-		'I': &Function{name: "IF", arity: 3, fn: if_},
+		'I': &Function{name: "IF", arity: 3, fn: if_, tail: ifTail},
 		'G': &Function{name: "GET", arity: 3, fn: get},
 
 		// Arity 4
 		'S': &Function{name: "SET", arity: 4, fn: set},
 	}
 
-	// stdinScanner is used by the `prompt` function to read lines from the standard input.
-	stdinScanner = bufio.NewScanner(os.Stdin)
+	// ExtensionFunctions is a list of all known extension functions, keyed by their full name. The
+	// Knight spec reserves word functions starting with `X` for extensions, so whenever the Parser
+	// encounters an `X`, it reads the entire word and looks it up here (instead of in KnownFunctions).
+	ExtensionFunctions = map[string]*Function{}
+
+	// Stdin is where functions which read input (such as `PROMPT`) read from. It defaults to the
+	// standard input, but can be replaced to provide input to Knight programs. It's a `bufio.Reader`
+	// so that `XEOF` can check whether there's input left without consuming it.
+	Stdin = bufio.NewReader(os.Stdin)
+
+	// Stdout is where functions which print (such as `OUTPUT` and `DUMP`) write to. It defaults to
+	// the standard output, but can be replaced to capture the output of Knight programs.
+	Stdout io.Writer = os.Stdout
+
+	// Stderr is where diagnostics (such as `XERROUT` and warnings) are written to. It defaults to
+	// the standard error, but can be replaced just like Stdout.
+	Stderr io.Writer = os.Stderr
+
+	// OutputLinesPerSecond limits how many times per second `OUTPUT` can be called. When it's
+	// positive, `OUTPUT` will sleep before writing if it was last called less than a
+	// `1/OutputLinesPerSecond` of a second ago. It's zero (ie unlimited) by default.
+	OutputLinesPerSecond = 0
+
+	// Now returns the current time. Functions which depend on the time (such as `XTIMEOUT`,
+	// `XTHROTTLE`, and `XTIME`) use it instead of calling time.Now directly, so that it can be
+	// replaced with a fake clock to make them deterministic.
+	Now = time.Now
+
+	// Sleep pauses for the given duration. Functions which wait (such as `XRETRY`, and `OUTPUT` when
+	// OutputLinesPerSecond is set) use it instead of calling time.Sleep directly, so that it can be
+	// replaced along with Now (eg with one that just advances a fake clock).
+	Sleep = time.Sleep
+
+	// lastOutput is when `OUTPUT` last wrote something. It's used to enforce OutputLinesPerSecond.
+	lastOutput time.Time
+
+	// ClampExitStatus controls what `QUIT` does with exit statuses outside of `0` to `255`. When
+	// false (the default), they're passed to the OS as-is, and most OSes only use the lowest 8 bits
+	// (so `QUIT 256` exits with status `0`). When true, a warning is written to stderr, and `255`
+	// is used instead, so that a nonzero status never turns into a successful one.
+	ClampExitStatus = false
+
+	// QuitMode controls what `QUIT` does. By default, it's Exit, which exits the entire process via
+	// `os.Exit` (so deferred functions aren't run, and programs embedding Knight are killed too).
+	// When it's ReturnError, `QUIT` instead returns a *QuitError, which makes Evaluate return it
+	// (wrapped), so that the embedding program can decide what to do.
+	QuitMode = Exit
+
+	// Sandboxed, when true, disables the functions which access the system outside of stdin and
+	// stdout: The backtick function (which runs shell commands), `XREADFILE`, `XWRITEFILE`, and
+	// `XAPPENDFILE` return an error instead. It's intended for running untrusted programs, and is
+	// false by default.
+	Sandboxed = false
+
+	// Deterministic, when true, makes programs produce the same output every time they're run (given
+	// the same input), for golden-output testing. Specifically:
+	//
+	//   - `RANDOM` and `XSAMPLE` use a random number generator with a fixed seed, so they return the
+	//     same sequence of results in each process.
+	//   - `XTIME` always returns `0`.
+	//   - The backtick function, `XREADFILE`, `XWRITEFILE`, and `XAPPENDFILE` return an error, as if
+	//     Sandboxed was enabled.
+	//
+	// Other functions which depend on the time (`XTIMEOUT`, `XTHROTTLE`, and `XDEBOUNCE`) aren't
+	// affected, as making them deterministic would change what they do; replace Now to control them.
+	// It's false by default.
+	Deterministic = false
+
+	// deterministicRandom is the random number generator used when Deterministic is enabled.
+	deterministicRandom = rand.New(rand.NewSource(0))
+)
+
+// randomInt63 returns a random non-negative int64, using deterministicRandom if Deterministic is
+// enabled, or the global (randomly-seeded) generator otherwise.
+func randomInt63() int64 {
+	if Deterministic {
+		return deterministicRandom.Int63()
+	}
+
+	return rand.Int63()
+}
+
+// randomIntn returns a random int from 0 up to (but not including) n, using the same generator as
+// randomInt63. It panics if n isn't positive.
+func randomIntn(n int) int {
+	if Deterministic {
+		return deterministicRandom.Intn(n)
+	}
+
+	return rand.Intn(n)
+}
+
+// checkSandbox returns an error if Sandboxed (or Deterministic) is enabled. It should be called by
+// functions which Sandboxed disables, before they do anything. The functionName argument is just
+// used for error messages.
+func checkSandbox(functionName string) error {
+	if Sandboxed {
+		return fmt.Errorf("'%s' is disabled when sandboxed", functionName)
+	}
+
+	if Deterministic {
+		return fmt.Errorf("'%s' is disabled when deterministic", functionName)
+	}
+
+	return nil
+}
+
+// QuitBehaviour is the type of QuitMode.
+type QuitBehaviour int
+
+const (
+	Exit        QuitBehaviour = iota // `QUIT` exits the process via `os.Exit`.
+	ReturnError                      // `QUIT` returns a *QuitError.
 )
 
+// QuitError is the error returned by `QUIT` when QuitMode is ReturnError. Code is the exit status
+// `QUIT` was given (after ClampExitStatus is applied). Use `errors.As` to check for it, as it's
+// wrapped by Evaluate (and by RuntimeError when RecordPositions is enabled).
+//
+// Extension functions which catch errors (such as `XTRY` and `XRETRY`) never catch it, so `QUIT`
+// always stops the program.
+type QuitError struct {
+	Code int
+}
+
+// Error returns a message containing the exit status.
+func (e *QuitError) Error() string {
+	return fmt.Sprintf("quit with status %d", e.Code)
+}
+
+// Arity returns the amount of arguments the function named name takes (eg `2` for `'+'`, or `1` for
+// `'D'`), and whether there is such a function in KnownFunctions. Word functions are looked up by
+// their first letter, as that's all the Parser looks at. For extension functions (which start with
+// `X`), use ExtensionArity instead.
+func Arity(name rune) (int, bool) {
+	function, ok := KnownFunctions[name]
+	if !ok {
+		return 0, false
+	}
+
+	return function.arity, true
+}
+
+// ExtensionArity is like Arity, except it looks up the extension function with the full name name
+// (eg `"XRANGE"`) in ExtensionFunctions.
+func ExtensionArity(name string) (int, bool) {
+	function, ok := ExtensionFunctions[name]
+	if !ok {
+		return 0, false
+	}
+
+	return function.arity, true
+}
+
 // Initialize the functions module. This both initializes the random number generator for `random`,
 // as well as registers extension functions.
 //
@@ -96,6 +263,10 @@ func init() {
 	// circular loop; I moved `system` out here to be consistent)
 	KnownFunctions['E'] = &Function{name: "EVAL", arity: 1, fn: eval}
 	KnownFunctions['`'] = &Function{name: "`", arity: 1, fn: system}
+	KnownFunctions['.'] = &Function{name: ".", arity: 2, fn: range_}
+
+	ExtensionFunctions["XDUMPSTRING"] = &Function{name: "XDUMPSTRING", arity: 1, fn: dumpString}
+	ExtensionFunctions["XRANGE"] = &Function{name: "XRANGE", arity: 3, fn: steppedRange}
 }
 
 /**************************************************************************************************
@@ -142,14 +313,15 @@ func emptyList(_ []Value) (Value, error) {
 
 // random returns a random Integer.
 //
-// As an extension, the go implementation supports random integers above the required 32767.
+// As an extension, the go implementation supports random integers above the required 32767. When
+// Deterministic is enabled, the same sequence of integers is returned in each process.
 //
 // ## Examples
 //
 //	DUMP RANDOM #=> 8015671084101644486
 func random(_ []Value) (Value, error) {
 	// Note that `rand` is seeded in this file's `init` function.
-	return Integer(rand.Int63()), nil // Go only has `Int63` for some reason...
+	return Integer(randomInt63()), nil // Go only has `Int63` for some reason...
 }
 
 // prompt reads a line from stdin, returning Null if stdin is empty.
@@ -165,20 +337,24 @@ func random(_ []Value) (Value, error) {
 //	DUMP PROMPT <stdin="">           #=> ""
 //	DUMP ; PROMPT PROMPT <stdin="">  #=> null
 func prompt(_ []Value) (Value, error) {
-	// If there was a problem getting the line, then we're either at the end of the file (which means
-	// we should return Null), or there was some problem like stdin was closed or permission denied.
-	if !stdinScanner.Scan() {
-		// EOF doesn't cause errors; this means there's a problem with stdin, like permission denied.
-		if err := stdinScanner.Err(); err != nil {
-			return nil, fmt.Errorf("unable to 'PROMPT': %v", err)
-		}
+	line, err := Stdin.ReadString('\n')
 
-		// EOF was reached, return null.
+	// If there was a problem getting the line, then we're either at the end of the file, or there
+	// was some problem like stdin was closed or permission denied.
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("unable to 'PROMPT': %v", err)
+	}
+
+	// EOF was reached before anything was read, return null. (If the last line doesn't end in a
+	// newline, it's still returned, and the next `PROMPT` returns null.)
+	if err == io.EOF && line == "" {
 		return Null{}, nil
 	}
 
-	// The line was scanned properly, return it.
-	return String(stdinScanner.Text()), nil
+	// The line was read properly, so remove the line ending and return it.
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return String(line), nil
 }
 
 /**************************************************************************************************
@@ -252,7 +428,7 @@ func head(args []Value) (Value, error) {
 			return nil, errors.New("empty string given to '['")
 		}
 
-		return String(container[0]), nil
+		return substring(container, 0, 1), nil
 
 	default:
 		return nil, fmt.Errorf("invalid type given to '[': %T", container)
@@ -294,7 +470,7 @@ func tail(args []Value) (Value, error) {
 			return nil, errors.New("empty string given to ']'")
 		}
 
-		return container[1:], nil
+		return substringFrom(container, 1), nil
 
 	default:
 		return nil, fmt.Errorf("invalid type given to ']': %T", container)
@@ -350,6 +526,11 @@ func call(args []Value) (Value, error) {
 	return block.Execute()
 }
 
+// callTail is the `tail` version of call: It returns the block to execute, instead of executing it.
+func callTail(args []Value) (Value, error) {
+	return args[0].Execute()
+}
+
 // quit exits the program with the given exit status code.
 //
 // ## Examples
@@ -359,9 +540,11 @@ func call(args []Value) (Value, error) {
 //	QUIT 12     # (exit with status 12)
 //	QUIT "017"  # (exit with status 17)
 //
+// If QuitMode is ReturnError, then a *QuitError is returned instead of exiting.
+//
 // ## Undefined Behaviour
 // As an extension, exit codes that can fit into an `int` are supported. (Although, the OS might
-// not let us return them.)
+// not let us return them.) If ClampExitStatus is enabled, they're replaced by `255` instead.
 //
 //	QUIT 12345  # (allowed, but the OS determines the exit status...)
 func quit(args []Value) (Value, error) {
@@ -370,6 +553,16 @@ func quit(args []Value) (Value, error) {
 		return nil, err
 	}
 
+	if ClampExitStatus && (exitStatus < 0 || 255 < exitStatus) {
+		fmt.Fprintf(Stderr, "warning: exit status %d is out of range, using 255 instead\n",
+			exitStatus)
+		exitStatus = 255
+	}
+
+	if QuitMode == ReturnError {
+		return nil, &QuitError{Code: exitStatus}
+	}
+
 	os.Exit(exitStatus)
 	panic("<unreachable>") // Go isn't powerful enough to recognize os.Exit never returns.
 }
@@ -425,12 +618,14 @@ func negate(args []Value) (Value, error) {
 	return Integer(-integer), nil
 }
 
-// length returns the length of its argument, converted to an array.
+// length returns the length of its argument, converted to an array. (As an exception, the length
+// of strings is in bytes if StringIndexing is Bytes.)
 //
 // ## Examples
 //
 //	DUMP LENGTH 123            #=> 3
 //	DUMP LENGTH "hello world"  #=> 11
+//	DUMP LENGTH "😁"           #=> 1
 //	DUMP LENGTH ++++,T,F,T,F,N #=> 5
 //
 // ## Undefined Behaviour
@@ -438,7 +633,16 @@ func negate(args []Value) (Value, error) {
 //
 //	DUMP LENGTH BLOCK foo      #!! error: cant convert to a list
 func length(args []Value) (Value, error) {
-	list, err := executeToSlice(args[0])
+	ran, err := args[0].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	if str, ok := ran.(String); ok {
+		return Integer(stringLength(str)), nil
+	}
+
+	list, err := ran.ToSlice()
 	if err != nil {
 		return nil, err
 	}
@@ -446,7 +650,7 @@ func length(args []Value) (Value, error) {
 	return Integer(len(list)), nil
 }
 
-// dump prints a debugging representation of its argument to stdout, then returns it.
+// dump writes a debugging representation of its argument to Stdout, then returns it.
 //
 // ## Examples
 //
@@ -457,20 +661,20 @@ func length(args []Value) (Value, error) {
 // ## Undefined Behaviour
 // As an extension, _all_ types can be passed to `DUMP`.
 //
-//	DUMP BLOCK + foo 2     #=> FnCall(%!c(string=+), Variable(foo), 2)
+//	DUMP BLOCK + foo 2     #=> FnCall(+, Variable(foo), 2)
 //
-// Any errors with writing to stdout are silently ignored.
+// Any errors with writing to Stdout are silently ignored.
 func dump(args []Value) (Value, error) {
 	value, err := args[0].Execute()
 	if err != nil {
 		return nil, err
 	}
 
-	value.Dump()
+	value.Dump(Stdout)
 	return value, nil
 }
 
-// output writes its argument to stdout and returns null. Normally, a newline is added after its
+// output writes its argument to Stdout and returns null. Normally, a newline is added after its
 // argument, however if the argument ends in a `\`, the backslash is removed and no newline is
 // printed.
 //
@@ -489,13 +693,33 @@ func dump(args []Value) (Value, error) {
 //
 //	OUTPUT BLOCK foo       #!! error: cant convert to a list
 //
-// Any errors with writing to stdout are silently ignored.
+// Any errors with writing to Stdout are silently ignored.
+//
+// If OutputLinesPerSecond is set, then this will sleep until enough time has passed since the last
+// `OUTPUT` before writing. If an `XTIMEOUT`'s time limit passes while sleeping, nothing is written,
+// and TimeLimitExceeded is returned.
 func output(args []Value) (Value, error) {
 	message, err := executeToString(args[0])
 	if err != nil {
 		return nil, err
 	}
 
+	if OutputLinesPerSecond > 0 {
+		interval := time.Second / time.Duration(OutputLinesPerSecond)
+		if err := sleep(lastOutput.Add(interval).Sub(Now())); err != nil {
+			return nil, err
+		}
+
+		lastOutput = Now()
+	}
+
+	writeOutput(Stdout, message)
+	return Null{}, nil
+}
+
+// writeOutput writes message to w the way `OUTPUT` does: With a trailing newline, unless message
+// ends in a `\`, in which case the backslash is removed and w is flushed (see flushWriter) instead.
+func writeOutput(w io.Writer, message string) {
 	// Get the last "rune" (go-speak for (ish) a unicode character), so we can compare it against a
 	// backslash to see if the string ends in `\`. (If it does, the Knight specs say it should be
 	// deleted and the normal newline that `OUTPUT` would print would be suppressed.)
@@ -506,16 +730,31 @@ func output(args []Value) (Value, error) {
 
 	// Check to see if the last character is a `\`, and if it is, print neither it nor the newline
 	if lastChr == '\\' {
-		fmt.Print(message[:len(message)-idx])
+		fmt.Fprint(w, message[:len(message)-idx])
 
-		// Since we're not printing a newline, we flush stdout so that the output is always visible.
+		// Since we're not printing a newline, we flush w so that the output is always visible.
 		// (The error is explicitly ignored to be consistent with how `fmt.Print{,ln}` works.)
-		_ = os.Stdout.Sync()
+		_ = flushWriter(w)
 	} else {
-		fmt.Println(message)
+		fmt.Fprintln(w, message)
 	}
+}
 
-	return Null{}, nil
+// flushWriter flushes w if it has a `Flush() error` method (such as a `*bufio.Writer`), or syncs it
+// if it has a `Sync() error` method (such as an `*os.File`). Other writers are left alone. Errors
+// from syncing are ignored, as the standard output often can't be synced (eg when it's a terminal).
+func flushWriter(w io.Writer) error {
+	switch w := w.(type) {
+	case interface{ Flush() error }:
+		return w.Flush()
+
+	case interface{ Sync() error }:
+		_ = w.Sync()
+		return nil
+
+	default:
+		return nil
+	}
 }
 
 // ascii is the equivalent of `chr()` and `ord()` functions in other languages. An error is returned
@@ -898,10 +1137,11 @@ func exponentiate(args []Value) (Value, error) {
 	}
 }
 
-// compare is a helper method for lessThan and greaterThan. It returns a negative, zero, or positive
-// integer depending on whether lhs is less than, equal to, or greater than the second. The
-// functionName argument is just used for error messages if an invalid type is provided.
-func compare(lhs, rhs Value, functionName rune) (int, error) {
+// compare is a helper method for lessThan and greaterThan (and extensions which order values). It
+// returns a negative, zero, or positive integer depending on whether lhs is less than, equal to, or
+// greater than the second. The functionName argument is just used for error messages if an invalid
+// type is provided.
+func compare(lhs, rhs Value, functionName string) (int, error) {
 	switch lhs := lhs.(type) {
 	case Integer:
 		rhs, err := rhs.ToInt()
@@ -963,7 +1203,7 @@ func compare(lhs, rhs Value, functionName rune) (int, error) {
 		return len(lhs) - len(rhs), nil
 
 	default:
-		return 0, fmt.Errorf("invalid type given to %q: %T", functionName, lhs)
+		return 0, fmt.Errorf("invalid type given to '%s': %T", functionName, lhs)
 	}
 }
 
@@ -995,7 +1235,7 @@ func lessThan(args []Value) (Value, error) {
 		return nil, err
 	}
 
-	cmp, err := compare(lhs, rhs, '<')
+	cmp, err := compare(lhs, rhs, "<")
 	if err != nil {
 		return nil, err
 	}
@@ -1019,7 +1259,7 @@ func greaterThan(args []Value) (Value, error) {
 		return nil, err
 	}
 
-	cmp, err := compare(lhs, rhs, '>')
+	cmp, err := compare(lhs, rhs, ">")
 	if err != nil {
 		return nil, err
 	}
@@ -1141,6 +1381,15 @@ func then(args []Value) (Value, error) {
 	return args[1].Execute()
 }
 
+// thenTail is the `tail` version of then: It returns the second argument, instead of executing it.
+func thenTail(args []Value) (Value, error) {
+	if _, err := args[0].Execute(); err != nil {
+		return nil, err
+	}
+
+	return args[1], nil
+}
+
 // assign is used to assign values to variables. The first argument must be a Variable, or an error
 // is returned. The second argument is evaluated, and after assignment is returned.
 //
@@ -1183,6 +1432,11 @@ func assign(args []Value) (Value, error) {
 func while(args []Value) (Value, error) {
 	// "loop forever" loops in golang are `for { ... }`
 	for {
+		// Check for `XTIMEOUT`s here too, as the condition and body might not be function calls.
+		if err := checkDeadline(); err != nil {
+			return nil, err
+		}
+
 		condition, err := executeToBool(args[0])
 		if err != nil {
 			return nil, err
@@ -1234,6 +1488,20 @@ func if_(args []Value) (Value, error) {
 	return args[2].Execute()
 }
 
+// ifTail is the `tail` version of if_: It returns the branch to execute, instead of executing it.
+func ifTail(args []Value) (Value, error) {
+	condition, err := executeToBool(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if condition {
+		return args[1], nil
+	}
+
+	return args[2], nil
+}
+
 // get returns a sublist/substring with start and length of the second and third arguments. It
 // returns an error if the start or length are negative, if `start + length` is larger than
 // the collection's length, or if a non-list/string element is provided.
@@ -1293,18 +1561,20 @@ func get(args []Value) (Value, error) {
 
 	switch collection := collection.(type) {
 	case String:
-		if len(collection) < stop {
-			return nil, fmt.Errorf("string index out of bounds for 'GET': %d < %d", len(collection), stop)
+		if stringLength(collection) < stop {
+			return nil, fmt.Errorf("string index out of bounds for 'GET': %d < %d",
+				stringLength(collection), stop)
 		}
 
-		return collection[start:stop], nil
+		return substring(collection, start, stop), nil
 
 	case List:
 		if len(collection) < stop {
 			return nil, fmt.Errorf("list index out of bounds for 'GET': %d < %d", len(collection), stop)
 		}
 
-		return collection[start:stop], nil
+		// Use a full slice expression so that appending to the sublist never overwrites `collection`.
+		return collection[start:stop:stop], nil
 
 	default:
 		return nil, fmt.Errorf("invalid type given to 'GET': %T", collection)
@@ -1382,8 +1652,9 @@ func set(args []Value) (Value, error) {
 
 	switch collection := collection.(type) {
 	case String:
-		if len(collection) < stop {
-			return nil, fmt.Errorf("string index out of bounds for 'SET': %d < %d", len(collection), stop)
+		collectionLength := stringLength(collection)
+		if collectionLength < stop {
+			return nil, fmt.Errorf("string index out of bounds for 'SET': %d < %d", collectionLength, stop)
 		}
 
 		replacement, err := executeToString(args[3])
@@ -1393,9 +1664,9 @@ func set(args []Value) (Value, error) {
 
 		// Use a string builder for efficiency's sake
 		var builder strings.Builder
-		builder.WriteString(string(collection[:start]))
+		builder.WriteString(string(substring(collection, 0, start)))
 		builder.WriteString(replacement)
-		builder.WriteString(string(collection[stop:]))
+		builder.WriteString(string(substringFrom(collection, stop)))
 		return String(builder.String()), nil
 
 	case List:
@@ -1435,13 +1706,176 @@ func eval(args []Value) (Value, error) {
 	return Evaluate(sourceCode)
 }
 
+// maxRangeLength is the most elements that `.` and `XRANGE` will return (which take up a gibibyte).
+// Without it, huge ranges would make them try to allocate more memory than exists, which crashes the
+// entire process (and can't be recovered from).
+const maxRangeLength = 1 << 26
+
+// stepRange returns a list of the integers from start up to, but not including, stop, counting by
+// step. It returns an error if step is zero, if it's in the opposite direction of stop, or if the
+// list would be longer than maxRangeLength. The functionName argument is just used for error
+// messages.
+func stepRange(start, stop, step int, functionName string) (List, error) {
+	if step == 0 {
+		return nil, fmt.Errorf("zero step given to '%s'", functionName)
+	}
+
+	if (start < stop && step < 0) || (stop < start && 0 < step) {
+		return nil, fmt.Errorf("step goes the wrong direction for '%s': %d", functionName, step)
+	}
+
+	// The amount of integers is computed up front, rather than checking `i < stop` after each step,
+	// as `i += step` could overflow near the largest and smallest integers. It's done with uints, as
+	// the distance between start and stop might not fit in an int.
+	var distance, stride uint
+	if 0 < step {
+		distance, stride = uint(stop)-uint(start), uint(step)
+	} else {
+		distance, stride = uint(start)-uint(stop), -uint(step)
+	}
+
+	count := distance / stride
+	if distance%stride != 0 {
+		count++
+	}
+
+	if maxRangeLength < count {
+		return nil, fmt.Errorf("range given to '%s' is too long: %d elements", functionName, count)
+	}
+
+	list := make(List, 0, count)
+	for i, n := start, uint(0); n < count; i, n = i+step, n+1 {
+		list = append(list, Integer(i))
+	}
+
+	return list, nil
+}
+
+// rangeDirection returns the step that `.` uses to go from start to stop: `-1` if start is larger
+// than stop, and `1` otherwise.
+func rangeDirection(start, stop int) int {
+	if stop < start {
+		return -1
+	}
+	return 1
+}
+
+// range_ returns a list of the integers/runes from its first argument up to (or down to, if the
+// first argument is larger), but not including, its second argument. It returns an error if the
+// first argument isn't an integer or string.
+//
+// ## Examples
+//
+//	DUMP . 1 4      #=> [1, 2, 3]
+//	DUMP . 4 4      #=> []
+//	DUMP . 4 1      #=> [4, 3, 2]
+//	DUMP . "a" "d"  #=> ["a", "b", "c"]
+//	DUMP . "δ" "α"  #=> ["δ", "γ", "β"]
+//
+// ## Undefined Behaviour
+// Only the first rune of strings is used:
+//
+//	DUMP . "ab" "cd" #=> ["a", "b"]
+//
+// Other forms of undefined behaviour yield errors:
+//
+//	DUMP . "" "a"   #!! error, empty string
+//	DUMP . TRUE 1   #!! error, invalid type
+//	DUMP . 0 9223372036854775807  #!! error, range is too long
+func range_(args []Value) (Value, error) {
+	start, err := args[0].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	switch start := start.(type) {
+	case Integer:
+		stop, err := executeToInt(args[1])
+		if err != nil {
+			return nil, err
+		}
+
+		return stepRange(int(start), stop, rangeDirection(int(start), stop), ".")
+
+	case String:
+		stop, err := executeToString(args[1])
+		if err != nil {
+			return nil, err
+		}
+
+		if start == "" || stop == "" {
+			return nil, errors.New("empty string given to '.'")
+		}
+
+		startRune, _ := utf8.DecodeRuneInString(string(start))
+		stopRune, _ := utf8.DecodeRuneInString(stop)
+
+		// Get the range of the runes as integers, and then convert each one back into a string.
+		runes, err := stepRange(int(startRune), int(stopRune),
+			rangeDirection(int(startRune), int(stopRune)), ".")
+		if err != nil {
+			return nil, err
+		}
+
+		for i, r := range runes {
+			runes[i] = String(rune(r.(Integer)))
+		}
+
+		return runes, nil
+
+	default:
+		return nil, fmt.Errorf("invalid type given to '.': %T", start)
+	}
+}
+
+// steppedRange converts all three of its arguments to integers, and returns a list of the integers
+// from the first argument up to (or down to), but not including, the second, counting by the third.
+//
+// ## Examples
+//
+//	DUMP XRANGE 0 10 3   #=> [0, 3, 6, 9]
+//	DUMP XRANGE 10 0 ~2  #=> [10, 8, 6, 4, 2]
+//	DUMP XRANGE 4 4 1    #=> []
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour in `XRANGE` yield errors:
+//
+//	DUMP XRANGE 0 10 0   #!! error, zero step
+//	DUMP XRANGE 0 10 ~1  #!! error, the step goes the wrong direction
+//	DUMP XRANGE 0 9223372036854775807 1  #!! error, range is too long
+func steppedRange(args []Value) (Value, error) {
+	start, err := executeToInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	stop, err := executeToInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	step, err := executeToInt(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	return stepRange(start, stop, step, "XRANGE")
+}
+
 // system converts its argument to a string, and then evaluates that as a shell command, returning
 // the stdout of it (less its trailing newline)
 //
 // ## Examples
 //
 // DUMP ` "ls" #=> "README.md\ngo\ngo.mod\nknight\nmain.go"
+//
+// When Sandboxed (or Deterministic) is enabled, an error is returned instead, without executing
+// the argument.
 func system(args []Value) (Value, error) {
+	if err := checkSandbox("`"); err != nil {
+		return nil, err
+	}
+
 	// Get the shell script to execute
 	shellCommand, err := executeToString(args[0])
 	if err != nil {
@@ -1472,3 +1906,20 @@ func system(args []Value) (Value, error) {
 	// Return the stdout
 	return String(stdout), nil
 }
+
+// dumpString returns the debugging representation of its argument as a String. It's identical to
+// `DUMP`, except the representation is returned instead of being printed.
+//
+// ## Examples
+//
+//	OUTPUT XDUMPSTRING "hi"          #=> "hi"
+//	DUMP XDUMPSTRING + @ 12          #=> "[1, 2]"
+//	OUTPUT XDUMPSTRING BLOCK + a 2   #=> FnCall(+, Variable(a), 2)
+func dumpString(args []Value) (Value, error) {
+	value, err := args[0].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	return String(dumpToString(value)), nil
+}
@@ -0,0 +1,166 @@
+package knight
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDumpAndOutputWriteToStdout(t *testing.T) {
+	defer func(stdout io.Writer) { Stdout = stdout }(Stdout)
+
+	for _, test := range []struct {
+		program  string
+		expected string
+	}{
+		{`OUTPUT "hello"`, "hello\n"},
+		{`OUTPUT ""`, "\n"},
+		{`OUTPUT "no newline\"`, "no newline"},
+		{`OUTPUT "\"`, ""},
+		{`OUTPUT 12`, "12\n"},
+		{`OUTPUT +@ 1`, "1\n"},
+		{`DUMP 'a"b\'`, `"a\"b\\"`},
+		{`DUMP 12`, "12"},
+		{`DUMP NULL`, "null"},
+		{`DUMP +@ "a"`, `["a"]`},
+		{`; DUMP 1 OUTPUT 2`, "12\n"},
+	} {
+		var buffer bytes.Buffer
+		Stdout = &buffer
+
+		if _, err := Evaluate(test.program); err != nil {
+			t.Errorf("%s: unexpected error: %v", test.program, err)
+			continue
+		}
+
+		if buffer.String() != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.program, test.expected, buffer.String())
+		}
+	}
+}
+
+// useFakeClock replaces Now and Sleep with a fake clock, which only advances when Sleep is called.
+// The returned function restores them.
+func useFakeClock() (restore func()) {
+	now := time.Unix(0, 0)
+	oldNow, oldSleep := Now, Sleep
+
+	Now = func() time.Time { return now }
+	Sleep = func(duration time.Duration) {
+		if duration > 0 {
+			now = now.Add(duration)
+		}
+	}
+
+	return func() { Now, Sleep = oldNow, oldSleep }
+}
+
+func TestOutputLinesPerSecond(t *testing.T) {
+	defer useFakeClock()()
+	defer func(stdout io.Writer) { Stdout = stdout }(Stdout)
+	defer func() { OutputLinesPerSecond, lastOutput = 0, time.Time{} }()
+
+	var buffer bytes.Buffer
+	Stdout = &buffer
+	OutputLinesPerSecond = 10
+	start := Now()
+
+	if _, err := Evaluate("; OUTPUT 1 ; OUTPUT 2 OUTPUT 3"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if buffer.String() != "1\n2\n3\n" {
+		t.Errorf("unexpected output: %q", buffer.String())
+	}
+
+	// The first line is written immediately, and each one after it waits a tenth of a second.
+	if elapsed := Now().Sub(start); elapsed != 200*time.Millisecond {
+		t.Errorf("expected 200ms to pass, but %v did", elapsed)
+	}
+}
+
+func TestOutputLinesPerSecondRespectsTimeout(t *testing.T) {
+	defer useFakeClock()()
+	defer func(stdout io.Writer) { Stdout = stdout }(Stdout)
+	defer func() { OutputLinesPerSecond, lastOutput = 0, time.Time{} }()
+
+	var buffer bytes.Buffer
+	Stdout = &buffer
+	OutputLinesPerSecond = 10
+	start := Now()
+
+	_, err := Evaluate("XTIMEOUT 150 ; OUTPUT 1 ; OUTPUT 2 OUTPUT 3")
+	if !errors.Is(err, TimeLimitExceeded) {
+		t.Fatalf("expected TimeLimitExceeded, got %v", err)
+	}
+
+	if buffer.String() != "1\n2\n" {
+		t.Errorf("unexpected output: %q", buffer.String())
+	}
+
+	// The third `OUTPUT` only sleeps until the deadline.
+	if elapsed := Now().Sub(start); elapsed != 150*time.Millisecond {
+		t.Errorf("expected 150ms to pass, but %v did", elapsed)
+	}
+}
+
+func TestStepRange(t *testing.T) {
+	for _, test := range []struct {
+		start, stop, step int
+		expected          List
+	}{
+		{0, 10, 3, List{Integer(0), Integer(3), Integer(6), Integer(9)}},
+		{0, 9, 3, List{Integer(0), Integer(3), Integer(6)}},
+		{10, 0, -3, List{Integer(10), Integer(7), Integer(4), Integer(1)}},
+		{3, 3, 1, List{}},
+		{3, 3, -1, List{}},
+		{math.MaxInt - 1, math.MaxInt, 2, List{Integer(math.MaxInt - 1)}},
+		{math.MaxInt - 2, math.MaxInt, 1, List{Integer(math.MaxInt - 2), Integer(math.MaxInt - 1)}},
+		{math.MinInt + 1, math.MinInt, -2, List{Integer(math.MinInt + 1)}},
+		{math.MinInt, math.MaxInt, math.MaxInt, List{Integer(math.MinInt), Integer(-1), Integer(math.MaxInt - 1)}},
+	} {
+		result, err := stepRange(test.start, test.stop, test.step, "XRANGE")
+		if err != nil {
+			t.Errorf("%d %d %d: unexpected error: %v", test.start, test.stop, test.step, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("%d %d %d: expected %s, got %s", test.start, test.stop, test.step,
+				dumpToString(test.expected), dumpToString(result))
+		}
+	}
+}
+
+func TestStepRangeTooLong(t *testing.T) {
+	for _, test := range [][3]int{
+		{0, math.MaxInt, 1},
+		{math.MaxInt, math.MinInt, -1},
+		{0, maxRangeLength + 1, 1},
+		{0, maxRangeLength*2 + 1, 2},
+	} {
+		if _, err := stepRange(test[0], test[1], test[2], "XRANGE"); err == nil {
+			t.Errorf("%v: expected an error", test)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	runEvaluateTests(t, []evaluateTest{
+		{". 1 5", List{Integer(1), Integer(2), Integer(3), Integer(4)}},
+		{". 5 1", List{Integer(5), Integer(4), Integer(3), Integer(2)}},
+		{". 3 3", List{}},
+		{". ~2 1", List{Integer(-2), Integer(-1), Integer(0)}},
+		{`. "a" "d"`, List{String("a"), String("b"), String("c")}},
+		{`. "α" "ε"`, List{String("α"), String("β"), String("γ"), String("δ")}},
+		{`. "δ" "α"`, List{String("δ"), String("γ"), String("β")}},
+		{`. "😀" "😃"`, List{String("😀"), String("😁"), String("😂")}},
+		{`. "ab" "cd"`, List{String("a"), String("b")}},
+		{`. "" "a"`, nil},
+		{`. TRUE 1`, nil},
+	})
+}
@@ -2,6 +2,7 @@ package knight
 
 import (
 	"fmt"
+	"io"
 	"strconv"
 )
 
@@ -14,9 +15,9 @@ type Integer int
 // Compile-time assertion that Integer implements the Value interface.
 var _ Value = Integer(0)
 
-// Dump prints the integer in base-10 to stdout.
-func (i Integer) Dump() {
-	fmt.Printf("%d", i)
+// Dump writes the integer in base-10 to w.
+func (i Integer) Dump(w io.Writer) {
+	fmt.Fprintf(w, "%d", i)
 }
 
 // Execute simply returns the integer unchanged.
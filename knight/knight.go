@@ -2,21 +2,59 @@ package knight
 
 import (
 	"fmt"
+	"io"
 )
 
-// Evaluate parses source as Knight code, and then executes it. Any errors that occur when parsing
-// or executing the code are returned.
-func Evaluate(source string) (Value, error) {
+// Parse parses source as Knight code, and returns it without executing it. This is useful for tools
+// which inspect Knight code; the returned Value can later be run via its Execute method.
+//
+// Like Evaluate, only the first value in source is parsed (as a Knight program is a single value),
+// and anything after it is ignored. (With CheckParens, unbalanced parentheses after it are still
+// errors, though.)
+func Parse(source string) (Value, error) {
 	parser := NewParser(source)
 
 	value, err := parser.ParseNextValue()
 	if err != nil {
-		return nil, fmt.Errorf("parse error: %v", err)
+		return nil, err
+	}
+
+	if err := parser.Finish(); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// ParseReader is like Parse, except the source is read from reader as it's parsed (see
+// NewReaderParser), so it never has to be in memory all at once. Any errors that occur when reading
+// from it are returned.
+func ParseReader(reader io.Reader) (Value, error) {
+	parser := NewReaderParser(reader)
+
+	value, err := parser.ParseNextValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := parser.Finish(); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// Evaluate parses source as Knight code (see Parse), and then executes it. Any errors that occur
+// when parsing or executing the code are returned.
+func Evaluate(source string) (Value, error) {
+	value, err := Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("parse error: %w", err)
 	}
 
 	result, err := value.Execute()
 	if err != nil {
-		return nil, fmt.Errorf("runtime error: %v", err)
+		return nil, fmt.Errorf("runtime error: %w", err)
 	}
 
 	return result, nil
@@ -0,0 +1,69 @@
+package knight
+
+import (
+	"reflect"
+	"testing"
+)
+
+// benchmarkProgram sums the numbers below 100, and (like most programs) calls lots of functions.
+const benchmarkProgram = "; = i 0 ; = sum 0 : WHILE < i 100 ; = sum + sum i : = i + i 1"
+
+// BenchmarkExecute executes an already-parsed program. The argument slices of its function calls
+// are allocated when it's parsed, and reused by every execution, so (unlike BenchmarkEvaluate) its
+// only allocations are of the Integers that don't fit in an interface without one.
+func BenchmarkExecute(b *testing.B) {
+	program, err := Parse(benchmarkProgram)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := program.Execute(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkEvaluate parses the program each time before executing it, for comparison.
+func BenchmarkEvaluate(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := Evaluate(benchmarkProgram); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// evaluateTest is a Knight program, and the value it should evaluate to. A nil expected value means
+// that the program should return an error instead.
+type evaluateTest struct {
+	program  string
+	expected Value
+}
+
+// runEvaluateTests evaluates each test's program, and checks that its result is the expected one
+// (compared via reflect.DeepEqual), or that it returned an error if none is expected.
+func runEvaluateTests(t *testing.T, tests []evaluateTest) {
+	t.Helper()
+
+	for _, test := range tests {
+		result, err := Evaluate(test.program)
+
+		if test.expected == nil {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %s", test.program, dumpToString(result))
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.program, err)
+		} else if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("%s: expected %s, got %s", test.program, dumpToString(test.expected), dumpToString(result))
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package knight
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Warning is a potential problem with a Knight program found by Lint.
+type Warning struct {
+	Variable string    // The name of the variable the warning is about.
+	Message  string    // A description of the problem.
+	Position *Position // Where the problem is, or nil if RecordPositions wasn't enabled when parsing.
+}
+
+// String returns the warning's message, prefixed by its line if its position is known (like syntax
+// errors are).
+func (w Warning) String() string {
+	if w.Position == nil {
+		return w.Message
+	}
+
+	return fmt.Sprintf("[line %d] %s", w.Position.Line, w.Message)
+}
+
+// assignedArguments are the indices of the arguments which functions assign to, rather than read,
+// keyed by function name. (See `assign`, `forEach`, `let`, and `exchange`.)
+var assignedArguments = map[string]int{
+	"=":         0,
+	"XFOREACH":  1,
+	"XLET":      0,
+	"XEXCHANGE": 0,
+}
+
+// Lint checks a parsed program (eg from Parse) for variables which are read, but are never assigned
+// anywhere in the program, which usually means that their name is misspelled. Executing such a
+// variable is always an error.
+//
+// This is a simple check, not a full analysis: A variable which is assigned anywhere (even after
+// it's read, or in code that never runs) isn't reported, and code run via `EVAL` isn't checked.
+// Variables which are currently assigned (eg via SetVariable) count as assigned, as do the
+// variables `_1`, `_2`, etc, which are assigned by extension functions (see eg `XTRY`).
+//
+// One Warning is returned for each such variable, at its first use, in the order they're used. If
+// RecordPositions was enabled when parsing, the position of the innermost function call the
+// variable is an argument of is included.
+func Lint(program Value) []Warning {
+	assigned := map[*Variable]bool{}
+	lintAssignments(program, assigned)
+
+	var warnings []Warning
+	reported := map[*Variable]bool{}
+	lintReads(program, nil, func(variable *Variable, position *Position) {
+		if assigned[variable] || reported[variable] || variable.load() != nil {
+			return
+		}
+
+		if isArgumentName(variable.name) {
+			return
+		}
+
+		reported[variable] = true
+		warnings = append(warnings, Warning{
+			Variable: variable.name,
+			Message:  fmt.Sprintf("variable %q is never assigned", variable.name),
+			Position: position,
+		})
+	})
+
+	return warnings
+}
+
+// isArgumentName returns whether name is one of the variables assigned by bindArguments.
+func isArgumentName(name string) bool {
+	digits := strings.TrimPrefix(name, "_")
+	return digits != name && digits != "" && strings.Trim(digits, "0123456789") == ""
+}
+
+// lintAssignments is a helper for Lint. It adds every variable that's assigned within value to
+// assigned.
+func lintAssignments(value Value, assigned map[*Variable]bool) {
+	fnCall, ok := value.(*FnCall)
+	if !ok {
+		return
+	}
+
+	if index, ok := assignedArguments[fnCall.function.name]; ok {
+		if variable, ok := fnCall.arguments[index].(*Variable); ok {
+			assigned[variable] = true
+		}
+	}
+
+	for _, argument := range fnCall.arguments {
+		lintAssignments(argument, assigned)
+	}
+}
+
+// lintReads is a helper for Lint. It calls read with every variable that's read within value (ie
+// every variable that isn't being assigned to), in the order they appear, along with the position
+// of the function call it's an argument of (which is position if value is the variable itself).
+func lintReads(value Value, position *Position, read func(*Variable, *Position)) {
+	switch value := value.(type) {
+	case *Variable:
+		read(value, position)
+
+	case *FnCall:
+		if value.position != nil {
+			position = value.position
+		}
+
+		assignedIndex, assigns := assignedArguments[value.function.name]
+
+		for i, argument := range value.arguments {
+			if assigns && i == assignedIndex {
+				if _, ok := argument.(*Variable); ok {
+					continue
+				}
+			}
+
+			lintReads(argument, position, read)
+		}
+	}
+}
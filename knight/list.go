@@ -2,6 +2,7 @@ package knight
 
 import (
 	"fmt"
+	"io"
 	"strings"
 )
 
@@ -9,25 +10,78 @@ import (
 //
 // Empty lists in Knight are represented via `@`. Lists can be created via `,` (eg `,3`), which
 // create a one-element list, or via coercions such as `+ @ 123` (which yields `[1, 2, 3]`.)
+//
+// Knight code can never modify a list in place, so lists share memory freely: `GET` and `]` return
+// sublists of their argument, `=` doesn't copy lists, `XSETIN` shares the nested lists it doesn't
+// change, and converting a list to a list returns it unchanged. Functions which build new lists,
+// such as `+`, `*`, and `SET`, always allocate a new backing array, but still share the elements
+// themselves. Go code which wants to modify a list it got from Knight should `Clone` it first.
 type List []Value
 
 // Compile-time assertion that List implements the Value interface.
 var _ Value = List{}
 
-// Dump prints a debugging representation of the list to stdout.
-func (l List) Dump() {
-	fmt.Print("[")
+// PrettyDump controls how lists are written by Dump (and so `DUMP`). When false (the default), lists
+// are written on a single line, as the Knight spec requires (eg `[1, [2, 3]]`). When true, nonempty
+// lists are written with one element per line, and nested lists are indented by two spaces, like a
+// JSON pretty-printer would:
+//
+//	[
+//	  1,
+//	  [
+//	    2,
+//	    3
+//	  ]
+//	]
+var PrettyDump = false
+
+// Dump writes a debugging representation of the list to w. See PrettyDump for how it's formatted.
+func (l List) Dump(w io.Writer) {
+	if PrettyDump {
+		l.prettyDump(w, "")
+		return
+	}
+
+	fmt.Fprint(w, "[")
 
 	for i, element := range l {
 		// Don't print a comma for the first argument
 		if i != 0 {
-			fmt.Print(", ")
+			fmt.Fprint(w, ", ")
 		}
 
-		element.Dump()
+		element.Dump(w)
 	}
 
-	fmt.Print("]")
+	fmt.Fprint(w, "]")
+}
+
+// prettyDump is the helper function for Dump when PrettyDump is enabled. The indent argument is the
+// indentation of the line the list starts on.
+func (l List) prettyDump(w io.Writer, indent string) {
+	if len(l) == 0 {
+		fmt.Fprint(w, "[]")
+		return
+	}
+
+	fmt.Fprint(w, "[\n")
+
+	for i, element := range l {
+		// Don't print a comma for the first element
+		if i != 0 {
+			fmt.Fprint(w, ",\n")
+		}
+
+		fmt.Fprint(w, indent+"  ")
+
+		if list, ok := element.(List); ok {
+			list.prettyDump(w, indent+"  ")
+		} else {
+			element.Dump(w)
+		}
+	}
+
+	fmt.Fprint(w, "\n"+indent+"]")
 }
 
 // Execute simply returns the list unchanged.
@@ -87,3 +141,20 @@ func (l List) Join(separator string) (string, error) {
 
 	return builder.String(), nil
 }
+
+// Clone returns a deep copy of the list: Any nested lists are cloned too, so that modifying the
+// result (or any list within it) never affects the original. Other values are immutable, and are
+// shared.
+func (l List) Clone() List {
+	clone := make(List, len(l))
+
+	for i, element := range l {
+		if list, ok := element.(List); ok {
+			element = list.Clone()
+		}
+
+		clone[i] = element
+	}
+
+	return clone
+}
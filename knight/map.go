@@ -0,0 +1,210 @@
+package knight
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Map is an extension type which associates string keys with values.
+//
+// Knight itself only has lists, which means looking something up by a key requires scanning through
+// the entire list. Maps are created via `XMAP`, and are manipulated via `XMSET`, `XMGET`, and
+// `XMHAS`. Like all other Knight values, Maps are never modified once they're created: `XMSET`
+// returns a new map. Keys are kept in the order they were first added.
+type Map struct {
+	keys   []string         // all the keys of the map, in the order they were first added.
+	values map[string]Value // the value for each key in `keys`.
+}
+
+// Compile-time assertion that Map implements the Value interface.
+var _ Value = Map{}
+
+// Register the map functions. (See `init` in `function.go` for more details.)
+func init() {
+	ExtensionFunctions["XMAP"] = &Function{name: "XMAP", arity: 0, fn: emptyMap}
+	ExtensionFunctions["XMSET"] = &Function{name: "XMSET", arity: 3, fn: mapSet}
+	ExtensionFunctions["XMGET"] = &Function{name: "XMGET", arity: 2, fn: mapGet}
+	ExtensionFunctions["XMHAS"] = &Function{name: "XMHAS", arity: 2, fn: mapHas}
+}
+
+// Dump writes a debugging representation of the map to w.
+func (m Map) Dump(w io.Writer) {
+	fmt.Fprint(w, "{")
+
+	for i, key := range m.keys {
+		// Don't print a comma for the first pair
+		if i != 0 {
+			fmt.Fprint(w, ", ")
+		}
+
+		fmt.Fprintf(w, "%q: ", key)
+		m.values[key].Dump(w)
+	}
+
+	fmt.Fprint(w, "}")
+}
+
+// Execute simply returns the map unchanged.
+func (m Map) Execute() (Value, error) {
+	return m, nil
+}
+
+// ToBool returns whether the map is nonempty.
+func (m Map) ToBool() (bool, error) {
+	return len(m.keys) != 0, nil
+}
+
+// ToInt returns the amount of keys in the map.
+func (m Map) ToInt() (int, error) {
+	return len(m.keys), nil
+}
+
+// ToString returns the same representation as Dump.
+func (m Map) ToString() (string, error) {
+	var builder strings.Builder
+	m.Dump(&builder)
+	return builder.String(), nil
+}
+
+// ToSlice returns a list of `[key, value]` pairs, in the same order as the map's keys. (This is an
+// association list, so it can be used with `XLOOKUP`.)
+func (m Map) ToSlice() ([]Value, error) {
+	pairs := make(List, len(m.keys))
+
+	for i, key := range m.keys {
+		pairs[i] = List{String(key), m.values[key]}
+	}
+
+	return pairs, nil
+}
+
+// executeToMap is a helper function which executes value and returns an error if it's not a Map.
+// The functionName argument is just used for error messages.
+func executeToMap(value Value, functionName string) (Map, error) {
+	ran, err := value.Execute()
+	if err != nil {
+		return Map{}, err
+	}
+
+	m, ok := ran.(Map)
+	if !ok {
+		return Map{}, fmt.Errorf("invalid type given to '%s': %T", functionName, ran)
+	}
+
+	return m, nil
+}
+
+// emptyMap always returns an empty Map.
+//
+// ## Examples
+//
+//	DUMP XMAP #=> {}
+func emptyMap(_ []Value) (Value, error) {
+	return Map{values: map[string]Value{}}, nil
+}
+
+// mapSet returns a copy of the map given as the first argument, where the key given as the second
+// argument (converted to a string) is associated with the third argument. If the key is new, it's
+// added after all the other keys; otherwise, it stays in the same place.
+//
+// ## Examples
+//
+//	DUMP XMSET XMAP "a" 1                  #=> {"a": 1}
+//	DUMP XMSET (XMSET XMAP 1 2) 1 3        #=> {"1": 3}
+//	DUMP XMSET (XMSET XMAP "a" 1) "b" @    #=> {"a": 1, "b": []}
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XMSET` yield errors:
+//
+//	DUMP XMSET @ "a" 1                     #!! error: invalid type
+//	DUMP XMSET XMAP (BLOCK a) 1            #!! error: cant convert to a string
+func mapSet(args []Value) (Value, error) {
+	m, err := executeToMap(args[0], "XMSET")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := executeToString(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := args[2].Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the map, so that the original isn't modified.
+	updated := Map{keys: m.keys, values: make(map[string]Value, len(m.values)+1)}
+	for k, v := range m.values {
+		updated.values[k] = v
+	}
+
+	if _, ok := m.values[key]; !ok {
+		// Use a full slice expression so that `append` never modifies `m.keys`' backing array.
+		updated.keys = append(m.keys[:len(m.keys):len(m.keys)], key)
+	}
+
+	updated.values[key] = value
+	return updated, nil
+}
+
+// mapGet returns the value associated with the key given as the second argument (converted to a
+// string) in the map given as the first, or Null if the key isn't in the map.
+//
+// ## Examples
+//
+//	DUMP XMGET (XMSET XMAP "a" 1) "a"      #=> 1
+//	DUMP XMGET (XMSET XMAP 1 2) 1          #=> 2
+//	DUMP XMGET XMAP "a"                    #=> null
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XMGET` yield errors:
+//
+//	DUMP XMGET @ "a"                       #!! error: invalid type
+func mapGet(args []Value) (Value, error) {
+	m, err := executeToMap(args[0], "XMGET")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := executeToString(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := m.values[key]
+	if !ok {
+		return Null{}, nil
+	}
+
+	return value, nil
+}
+
+// mapHas returns whether the key given as the second argument (converted to a string) is in the map
+// given as the first.
+//
+// ## Examples
+//
+//	DUMP XMHAS (XMSET XMAP "a" NULL) "a"   #=> true
+//	DUMP XMHAS XMAP "a"                    #=> false
+//
+// ## Undefined Behaviour
+// All forms of undefined behaviour within `XMHAS` yield errors:
+//
+//	DUMP XMHAS @ "a"                       #!! error: invalid type
+func mapHas(args []Value) (Value, error) {
+	m, err := executeToMap(args[0], "XMHAS")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := executeToString(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	_, ok := m.values[key]
+	return Boolean(ok), nil
+}
@@ -2,6 +2,7 @@ package knight
 
 import (
 	"fmt"
+	"io"
 )
 
 // Null is the null type within knight.
@@ -13,9 +14,9 @@ type Null struct{} // empty struct because there's only ever one null
 // Compile-time assertion that Null implements the Value interface.
 var _ Value = Null{}
 
-// Dump simply prints "null" to stdout.
-func (_ Null) Dump() {
-	fmt.Print("null")
+// Dump simply writes "null" to w.
+func (_ Null) Dump(w io.Writer) {
+	fmt.Fprint(w, "null")
 }
 
 // Execute simply returns the null unchanged.
@@ -1,9 +1,12 @@
 package knight
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
+	"strings"
 	"unicode"
 )
 
@@ -13,6 +16,51 @@ import (
 // or didn't provide enough arguments to a function (eg `DUMP + 1`).
 var EndOfInput = errors.New("source was empty")
 
+// StrictWordFunctions, when true, makes the Parser return an error when a word function is spelled
+// out with more than one letter, but isn't spelled exactly right (eg `DERP` instead of `DUMP`).
+//
+// The Knight spec says that only the first letter of word functions matters, and the rest are
+// ignored (so `DERP` is just another way to write `DUMP`), which is why this is false by default.
+// However, that means typos silently run the wrong function, which this catches. Single-letter
+// forms (eg `D`) are always allowed.
+var StrictWordFunctions = false
+
+// StrictWhitespace, when true, makes the Parser (and `XCALC`) only treat the whitespace characters
+// the Knight spec defines (tab, newline, carriage return, and space) as whitespace. Other
+// characters, such as non-breaking spaces, are then syntax errors.
+//
+// By default, it's false, and everything Unicode considers whitespace is ignored, as an extension.
+var StrictWhitespace = false
+
+// StringEscapes, when true, makes the Parser handle the escape sequences `\n` (newline), `\t` (tab),
+// `\\` (backslash), and `\"` (double quote) within double-quoted strings. Backslashes followed by
+// anything else are left as-is, as are all backslashes within single-quoted strings.
+//
+// The Knight spec doesn't have escape sequences (a backslash is just a backslash, and strings end
+// at the first matching quote), which is why this is false by default.
+var StringEscapes = false
+
+// CheckParens, when true, makes the Parser return an error for unbalanced parentheses: Either a
+// `)` without a matching `(`, or a `(` which is never closed. The line of the offending parenthesis
+// is included in the error.
+//
+// Normally, parentheses are treated as whitespace, as the Knight spec allows. (Balancing them is an
+// optional extension the spec permits.) Only their balance is checked, not whether they surround
+// exactly one expression.
+var CheckParens = false
+
+// RecordPositions, when true, makes the Parser record where in the source code each function call
+// starts, so that runtime errors can include the line they happened on (see RuntimeError), and so
+// that tools can map function calls back to the source code (see FnCall.Position). It's false by
+// default, as it costs an extra allocation per function call.
+var RecordPositions = false
+
+// Position is a location within source code.
+type Position struct {
+	Index int // The index of the rune (not byte) the position is at.
+	Line  int // The line the position is on. Like syntax errors, the first line is line `0`.
+}
+
 // Parser is used to construct Values from source code.
 //
 // This parses Knight programs in terms of "rune"s (golang speak for unicode codepoints), instead of
@@ -21,6 +69,10 @@ var EndOfInput = errors.New("source was empty")
 // index into a UTF-8 encoded string like we could of a slice of bytes. So, we have to convert the
 // input into a rune slice (`[]rune`), which allows us to access the characters one-at-at-time.
 //
+// Parsers created via NewReaderParser instead read runes from an io.Reader as they're needed, so
+// that the entire program never has to be in memory at once. Only the runes of the value currently
+// being parsed (and those after it that have been peeked at) are kept around.
+//
 // The strategy we use to parse out knight programs is to examine each rune at a time from the
 // source, which lets us know what to do next. Knight's spec is designed so that the next expression
 // is always unambiguously determined by the first non-whitespace non-comment rune. (e.g. if
@@ -28,6 +80,17 @@ var EndOfInput = errors.New("source was empty")
 type Parser struct {
 	source []rune // the contents of the program. (rune is golang speak for a "unicode character")
 	index  int    // index of the next rune to look at.
+	line   int    // the line number of the next rune to look at.
+
+	// When parsing from an io.Reader, source only contains the runes which have been read and not
+	// yet discarded; offset is the index of source's first rune. readErr is the error (if any) that
+	// reading ended with, other than io.EOF.
+	reader  *bufio.Reader
+	offset  int
+	readErr error
+
+	// The lines of the `(`s that haven't been closed yet. Only used when CheckParens is enabled.
+	openParens []int
 }
 
 // NewParser creates a Parser for the given source string.
@@ -35,22 +98,69 @@ func NewParser(source string) Parser {
 	return Parser{source: []rune(source), index: 0}
 }
 
-// IsAtEnd returns whether the parser is at the end of its stream.
-func (p *Parser) IsAtEnd() bool {
-	return len(p.source) <= p.index
+// NewReaderParser creates a Parser which reads its source from reader, a rune at a time, as it's
+// needed. Syntax errors are the same as they are for NewParser. If reading from reader fails, then
+// the error is returned by ParseNextValue.
+func NewReaderParser(reader io.Reader) Parser {
+	return Parser{reader: bufio.NewReader(reader)}
 }
 
-// linenoAt returns the line number at the given index. It's used in syntax error messages.
-func (p *Parser) linenoAt(index int) int {
-	lineno := 0
+// IsAtEnd returns whether the parser is at the end of its stream. When reading from an io.Reader,
+// this reads the next rune into source if it hasn't been already.
+func (p *Parser) IsAtEnd() bool {
+	if p.index-p.offset < len(p.source) {
+		return false
+	}
+
+	if p.reader == nil {
+		return true
+	}
 
-	for i := 0; i < index; i++ {
-		if p.source[i] == '\n' {
-			lineno++
+	r, _, err := p.reader.ReadRune()
+	if err != nil {
+		if err != io.EOF {
+			p.readErr = err
 		}
+
+		p.reader = nil // Don't try reading again.
+		return true
 	}
 
-	return lineno
+	p.source = append(p.source, r)
+	return false
+}
+
+// discardThreshold is how many already-parsed runes a Parser reading from an io.Reader keeps before
+// discarding them. (Discarding them after every value would mean copying the rest each time.)
+const discardThreshold = 4096
+
+// discardParsed discards the runes before the current index when reading from an io.Reader, if
+// there's enough of them. It must only be called between values, as TakeWhile and snippetAt use
+// the runes of the value being parsed.
+func (p *Parser) discardParsed() {
+	if p.reader == nil {
+		return
+	}
+
+	if parsed := p.index - p.offset; discardThreshold <= parsed {
+		p.source = append(p.source[:0], p.source[parsed:]...)
+		p.offset = p.index
+	}
+}
+
+// snippetLength is the maximum amount of runes returned by snippetAt.
+const snippetLength = 20
+
+// snippetAt returns the source code starting at index as a quoted string (so that newlines and such
+// are visible), truncated to snippetLength runes. It's used in syntax error messages.
+func (p *Parser) snippetAt(index int) string {
+	index -= p.offset
+
+	if len(p.source) <= index+snippetLength {
+		return strconv.Quote(string(p.source[index:]))
+	}
+
+	return strconv.Quote(string(p.source[index:index+snippetLength])) + "..."
 }
 
 // Peek returns the next rune without consuming it. It panics at the end of the source.
@@ -59,7 +169,7 @@ func (p *Parser) Peek() rune {
 		panic("<INTERNAL BUG> peeked when there's nothing left")
 	}
 
-	return p.source[p.index]
+	return p.source[p.index-p.offset]
 }
 
 // Advance consumes the next rune. It panics at the end of the source
@@ -68,6 +178,10 @@ func (p *Parser) Advance() {
 		panic("<INTERNAL BUG> advanced when there's nothing left")
 	}
 
+	if p.Peek() == '\n' {
+		p.line++
+	}
+
 	p.index++
 }
 
@@ -82,7 +196,99 @@ func (p *Parser) TakeWhile(condition func(rune) bool) string {
 
 	// (Since our `source` is a `[]rune`, and not a `string`, we have to convert it back to a
 	// `string` before returning it. We use the `string()` function to do this.)
-	return string(p.source[start:p.index])
+	return string(p.source[start-p.offset : p.index-p.offset])
+}
+
+// takeEscapedString is used by ParseNextValue when StringEscapes is enabled. It's like TakeWhile
+// with a condition of "isn't `quote`", except that escape sequences (see StringEscapes) are
+// replaced with what they represent, and an escaped `quote` doesn't end the string.
+func (p *Parser) takeEscapedString(quote rune) string {
+	var builder strings.Builder
+
+	for !p.IsAtEnd() && p.Peek() != quote {
+		c := p.Peek()
+		p.Advance()
+
+		if c != '\\' || p.IsAtEnd() {
+			builder.WriteRune(c)
+			continue
+		}
+
+		switch escaped := p.Peek(); escaped {
+		case 'n':
+			builder.WriteRune('\n')
+		case 't':
+			builder.WriteRune('\t')
+		case '\\', '"':
+			builder.WriteRune(escaped)
+		default:
+			// Unknown escape sequences are left as-is; the next iteration writes `escaped`.
+			builder.WriteRune('\\')
+			continue
+		}
+
+		p.Advance()
+	}
+
+	return builder.String()
+}
+
+// checkParen keeps track of the balance of parentheses when CheckParens is enabled. It should be
+// called with each rune that's about to be skipped as whitespace, and returns an error if the rune
+// is a `)` without a matching `(`.
+func (p *Parser) checkParen(c rune) error {
+	if !CheckParens {
+		return nil
+	}
+
+	switch c {
+	case '(':
+		p.openParens = append(p.openParens, p.line)
+
+	case ')':
+		if len(p.openParens) == 0 {
+			return fmt.Errorf("[line %d] unmatched ')'", p.line)
+		}
+
+		p.openParens = p.openParens[:len(p.openParens)-1]
+	}
+
+	return nil
+}
+
+// Finish should be called after the last call to ParseNextValue. When CheckParens is enabled, it
+// consumes the whitespace, comments, and parentheses that come after the last value, and then
+// returns an error if any parentheses are unbalanced. (Anything else after the last value is
+// ignored, as it always has been.) When CheckParens is disabled, it does nothing.
+func (p *Parser) Finish() error {
+	if !CheckParens {
+		return nil
+	}
+
+	for !p.IsAtEnd() {
+		c := p.Peek()
+
+		if c == '#' {
+			_ = p.TakeWhile(isntNewLine)
+			continue
+		}
+
+		if !isWhitespace(c) && c != '(' && c != ')' {
+			break
+		}
+
+		if err := p.checkParen(c); err != nil {
+			return err
+		}
+
+		p.Advance()
+	}
+
+	if len(p.openParens) != 0 {
+		return fmt.Errorf("[line %d] unmatched '('", p.openParens[0])
+	}
+
+	return nil
 }
 
 // Functions used within ParseNextValue as arguments to TakeWhile.
@@ -91,13 +297,28 @@ func isDigit(r rune) bool                 { return '0' <= r && r <= '9' }
 func isVariableStart(r rune) bool         { return unicode.IsLower(r) || r == '_' }
 func isVariableBody(r rune) bool          { return isVariableStart(r) || unicode.IsNumber(r) }
 func isWordFunctionCharacter(r rune) bool { return unicode.IsUpper(r) || r == '_' }
-func isWhitespace(r rune) bool            { return unicode.IsSpace(r) }
+
+// isWhitespace returns whether r is whitespace, which depends upon StrictWhitespace.
+func isWhitespace(r rune) bool {
+	if StrictWhitespace {
+		return r == '\t' || r == '\n' || r == '\r' || r == ' '
+	}
+
+	return unicode.IsSpace(r)
+}
 
 // ParseNextValue returns the next Value in the source code. EndOfInput is returned if there's no
-// Values left. Syntax errors (such as missing an ending quote) are also returned.
+// Values left. Syntax errors (such as missing an ending quote) are also returned, as are errors from
+// reading the source, for Parsers created via NewReaderParser.
 func (p *Parser) ParseNextValue() (Value, error) {
-	// If we're at the end, return the EndOfInput error.
+	p.discardParsed()
+
+	// If we're at the end, return the EndOfInput error (unless reading the source failed).
 	if p.IsAtEnd() {
+		if p.readErr != nil {
+			return nil, p.readErr
+		}
+
 		return nil, EndOfInput
 	}
 
@@ -106,8 +327,8 @@ func (p *Parser) ParseNextValue() (Value, error) {
 	// in the input stream.
 	c := p.Peek()
 
-	// The starting index of this expression. Used in some syntax error messages.
-	startIndex := p.index
+	// The starting index and line of this expression. Used in some syntax error messages.
+	startIndex, startLine := p.index, p.line
 
 	// Whitespace, delete it and parse again.
 	//
@@ -116,6 +337,10 @@ func (p *Parser) ParseNextValue() (Value, error) {
 	// syntax errors on unbalanced parenthesis if they want. However, for implementations that aren't
 	// doing that extension (like this one), they may safely be ignored)
 	if isWhitespace(c) || c == '(' || c == ')' {
+		if err := p.checkParen(c); err != nil {
+			return nil, err
+		}
+
 		p.Advance()
 		return p.ParseNextValue()
 	}
@@ -144,11 +369,22 @@ func (p *Parser) ParseNextValue() (Value, error) {
 		quote := c  // Save `c` in a variable with a more descriptive name.
 
 		// Read until we hit the ending quote, but don't actually consume it.
-		contents := p.TakeWhile(func(r rune) bool { return r != quote })
+		var contents string
+		if StringEscapes && quote == '"' {
+			contents = p.takeEscapedString(quote)
+		} else {
+			contents = p.TakeWhile(func(r rune) bool { return r != quote })
+		}
 
-		// If we reached end of file, that means we never found the ending quote.
+		// If we reached end of file, that means we never found the ending quote. Include the start
+		// of the string in the error, to make it easier to find in large programs.
 		if p.IsAtEnd() {
-			return nil, fmt.Errorf("[line %d] unterminated %q string", p.linenoAt(startIndex), quote)
+			if p.readErr != nil {
+				return nil, p.readErr
+			}
+
+			return nil, fmt.Errorf("[line %d] unterminated %q string: %s",
+				startLine, quote, p.snippetAt(startIndex+1))
 		}
 
 		// Consume the ending quote, and return the contents of the string.
@@ -160,18 +396,43 @@ func (p *Parser) ParseNextValue() (Value, error) {
 	// Everything else is a function, or invalid (which we check for below).
 	//
 
-	// Delete the function name out of the input stream
-	if isWordFunctionCharacter(c) {
-		_ = p.TakeWhile(isWordFunctionCharacter) // ignore the remainder of the word function
+	// Extension functions start with `X`, and are identified by their entire name, not just the
+	// first character. (Note: `ExtensionFunctions` is declared within `function.go`.)
+	var function *Function
+	var ok bool
+
+	if c == 'X' {
+		name := p.TakeWhile(isWordFunctionCharacter)
+
+		function, ok = ExtensionFunctions[name]
+		if !ok {
+			return nil, fmt.Errorf("[line %d] unknown extension function: %s", startLine, name)
+		}
 	} else {
-		p.Advance()
+		// Delete the function name out of the input stream
+		var word string
+		if isWordFunctionCharacter(c) {
+			word = p.TakeWhile(isWordFunctionCharacter) // the remainder is ignored unless strict
+		} else {
+			p.Advance()
+		}
+
+		// Get the function definition; If it doesn't exist, then we've been given an invalid token.
+		// (Note: `KnownFunctoins` is declared within `function.go`.)
+		function, ok = KnownFunctions[c]
+		if !ok {
+			return nil, fmt.Errorf("[line %d] unknown token start: %c", startLine, c)
+		}
+
+		if StrictWordFunctions && 1 < len(word) && word != function.name {
+			return nil, fmt.Errorf("[line %d] misspelled function %s (did you mean %s?)",
+				startLine, word, function.name)
+		}
 	}
 
-	// Get the function definition; If it doesn't exist, then we've been given an invalid token.
-	// (Note: `KnownFunctoins` is declared within `function.go`.)
-	function, ok := KnownFunctions[c]
-	if !ok {
-		return nil, fmt.Errorf("[line %d] unknown token start: %c", p.linenoAt(startIndex), c)
+	var position *Position
+	if RecordPositions {
+		position = &Position{Index: startIndex, Line: startLine}
 	}
 
 	// Create a slice with enough room to store all the arguments.
@@ -188,12 +449,14 @@ func (p *Parser) ParseNextValue() (Value, error) {
 			// Special case: If the error was EndOfInput, provide a better error message.
 			if err == EndOfInput {
 				err = fmt.Errorf("[line %d] missing argument %d for function %q",
-					p.linenoAt(startIndex), i+1, function.name)
+					startLine, i+1, function.name)
 			}
 
 			return nil, err
 		}
 	}
 
-	return NewFnCall(function, arguments), nil
+	fnCall := NewFnCall(function, arguments)
+	fnCall.position = position
+	return fnCall, nil
 }
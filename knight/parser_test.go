@@ -0,0 +1,47 @@
+package knight
+
+import "testing"
+
+func TestStringEscapes(t *testing.T) {
+	defer func(stringEscapes bool) { StringEscapes = stringEscapes }(StringEscapes)
+
+	for _, test := range []struct {
+		source        string
+		stringEscapes bool
+		expected      Value // nil if an error is expected
+	}{
+		{`"a\nb"`, false, String(`a\nb`)},
+		{`"a\"`, false, String(`a\`)},
+		{`'a\'`, false, String(`a\`)},
+
+		{`"a\nb"`, true, String("a\nb")},
+		{`"a\tb"`, true, String("a\tb")},
+		{`"a\\b"`, true, String(`a\b`)},
+		{`"a\"b"`, true, String(`a"b`)},
+		{`"a\qb"`, true, String(`a\qb`)},
+		{`"a\\"`, true, String(`a\`)},
+		{`'a\nb'`, true, String(`a\nb`)},
+		{`'a\'`, true, String(`a\`)},
+		{`"a\"`, true, nil},
+	} {
+		StringEscapes = test.stringEscapes
+
+		parser := NewParser(test.source)
+		value, err := parser.ParseNextValue()
+
+		if test.expected == nil {
+			if err == nil {
+				t.Errorf("%s (StringEscapes=%t): expected an error, got %#v",
+					test.source, test.stringEscapes, value)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s (StringEscapes=%t): unexpected error: %v", test.source, test.stringEscapes, err)
+		} else if value != test.expected {
+			t.Errorf("%s (StringEscapes=%t): expected %#v, got %#v",
+				test.source, test.stringEscapes, test.expected, value)
+		}
+	}
+}
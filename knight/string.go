@@ -2,6 +2,7 @@ package knight
 
 import (
 	"fmt"
+	"io"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -17,10 +18,88 @@ type String string
 // Compile-time assertion that String implements the Value interface.
 var _ Value = String("")
 
-// Dump prints the escaped version of string to stdout.
-func (s String) Dump() {
+// StringIndexingMode is the type of StringIndexing.
+type StringIndexingMode int
+
+const (
+	Runes StringIndexingMode = iota // Strings are indexed by rune (unicode codepoint).
+	Bytes                           // Strings are indexed by byte (of their UTF-8 encoding).
+)
+
+// StringIndexing controls whether the functions which index into strings (`GET`, `SET`, `[`, `]`,
+// and `LENGTH`, as well as `XTAKE`, `XDROP`, and `XAT`) count runes or bytes.
+//
+// The default is Runes, so that strings containing non-ASCII characters (eg `"😁"`) can be used
+// without splitting characters in half. Bytes is useful when interacting with byte-oriented
+// systems, but the strings it returns might not be valid UTF-8. (For strings that are entirely
+// ASCII, the two modes are identical.) Regardless of the mode, converting a string to a list (eg
+// `+ @ "😁"`) always splits it into runes.
+var StringIndexing = Runes
+
+// stringLength returns the length of s, in either runes or bytes depending on StringIndexing.
+func stringLength(s String) int {
+	if StringIndexing == Bytes {
+		return len(s)
+	}
+
+	return utf8.RuneCountInString(string(s))
+}
+
+// substring returns the part of s from start up to (but not including) stop, where both are
+// indices of either runes or bytes depending on StringIndexing. It panics if they're out of bounds.
+//
+// It slices s instead of copying it, so it takes time proportional to stop (not to the length of
+// s), and doesn't allocate.
+func substring(s String, start, stop int) String {
+	if StringIndexing == Bytes {
+		return s[start:stop]
+	}
+
+	startOffset := runeOffset(string(s), start)
+	stopOffset := startOffset + runeOffset(string(s[startOffset:]), stop-start)
+	return s[startOffset:stopOffset]
+}
+
+// substringFrom is like substring, except it returns the rest of s after start. As it doesn't need
+// to find the end of s, it takes time proportional to start.
+func substringFrom(s String, start int) String {
+	if StringIndexing == Bytes {
+		return s[start:]
+	}
+
+	return s[runeOffset(string(s), start):]
+}
+
+// runeOffset returns the byte offset of the rune at index in s. It panics if index is negative or
+// larger than the amount of runes in s.
+func runeOffset(s string, index int) int {
+	if index < 0 {
+		panic(fmt.Sprint("<INTERNAL BUG> negative rune index: ", index))
+	}
+
+	offset := 0
+	for ; index > 0; index-- {
+		if len(s) <= offset {
+			panic(fmt.Sprint("<INTERNAL BUG> rune index out of bounds: ", index))
+		}
+
+		// ASCII characters are always one byte, so there's no need to decode them.
+		if s[offset] < utf8.RuneSelf {
+			offset++
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(s[offset:])
+		offset += size
+	}
+
+	return offset
+}
+
+// Dump writes the escaped version of string to w.
+func (s String) Dump(w io.Writer) {
 	// It just so happens that golang's `%q` specifier exactly matches what Knight's `DUMP` expects.
-	fmt.Printf("%q", s)
+	fmt.Fprintf(w, "%q", s)
 }
 
 // Execute simply returns the Stirng unchanged.
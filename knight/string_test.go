@@ -0,0 +1,62 @@
+package knight
+
+import (
+	"testing"
+)
+
+func TestSubstring(t *testing.T) {
+	for _, test := range []struct {
+		s           String
+		start, stop int
+		expected    String
+	}{
+		{"hello", 1, 3, "el"},
+		{"hello", 0, 5, "hello"},
+		{"hello", 5, 5, ""},
+		{"", 0, 0, ""},
+		{"a😁bé", 1, 3, "😁b"},
+		{"a😁bé", 3, 4, "é"},
+		{"😁😁😁", 0, 2, "😁😁"},
+	} {
+		if result := substring(test.s, test.start, test.stop); result != test.expected {
+			t.Errorf("%q[%d:%d]: expected %q, got %q", test.s, test.start, test.stop, test.expected, result)
+		}
+
+		if test.stop == stringLength(test.s) {
+			if result := substringFrom(test.s, test.start); result != test.expected {
+				t.Errorf("%q[%d:]: expected %q, got %q", test.s, test.start, test.expected, result)
+			}
+		}
+	}
+}
+
+func TestSubstringBytes(t *testing.T) {
+	StringIndexing = Bytes
+	defer func() { StringIndexing = Runes }()
+
+	if result := substring("a😁b", 1, 5); result != "😁" {
+		t.Errorf("expected %q, got %q", "😁", result)
+	}
+
+	if result := substringFrom("a😁b", 5); result != "b" {
+		t.Errorf("expected %q, got %q", "b", result)
+	}
+}
+
+// BenchmarkStringTail removes the first character of a string until it's empty, which should take
+// time proportional to the length of the string (not its square).
+func BenchmarkStringTail(b *testing.B) {
+	program, err := Parse(`; = s * "abcdé" 2000 : WHILE s : = s ] s`)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := program.Execute(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package knight
 
+import (
+	"io"
+	"strings"
+)
+
 // Value is the interface implemented by all types that are usable in Knight programs.
 //
 // This not only includes the Integer, String, Boolean, Null, and List types that the spec defines,
@@ -20,8 +25,8 @@ package knight
 //
 //	Knight programs won't access them.
 type Value interface {
-	// Dump writes a debugging representation of the value to stdout.
-	Dump()
+	// Dump writes a debugging representation of the value to w.
+	Dump(w io.Writer)
 
 	// Execute executes the value, returning the result or whatever error may have occurred.
 	Execute() (Value, error)
@@ -83,3 +88,10 @@ func executeToSlice(value Value) (List, error) {
 
 	return ran.ToSlice()
 }
+
+// dumpToString is a helper function that returns what Value.Dump would have written.
+func dumpToString(value Value) string {
+	var builder strings.Builder
+	value.Dump(&builder)
+	return builder.String()
+}
@@ -0,0 +1,46 @@
+package knight
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	for _, test := range []struct {
+		value    Value
+		expected string
+	}{
+		{Null{}, "null"},
+		{Boolean(true), "true"},
+		{Boolean(false), "false"},
+		{Integer(0), "0"},
+		{Integer(-12), "-12"},
+		{String(""), `""`},
+		{String("a\"b\\c\n\t"), `"a\"b\\c\n\t"`},
+		{List{}, "[]"},
+		{List{Integer(1), String("a"), List{Null{}, Boolean(true)}}, `[1, "a", [null, true]]`},
+		{NewVariable("dump_test"), "Variable(dump_test)"},
+		{NewFnCall(KnownFunctions['+'], []Value{Integer(1), NewVariable("x")}),
+			"FnCall(+, 1, Variable(x))"},
+	} {
+		var buffer bytes.Buffer
+		test.value.Dump(&buffer)
+
+		if buffer.String() != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, buffer.String())
+		}
+	}
+}
+
+func TestPrettyDump(t *testing.T) {
+	PrettyDump = true
+	defer func() { PrettyDump = false }()
+
+	var buffer bytes.Buffer
+	List{Integer(1), List{Integer(2), Integer(3)}, List{}}.Dump(&buffer)
+
+	expected := "[\n  1,\n  [\n    2,\n    3\n  ],\n  []\n]"
+	if buffer.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buffer.String())
+	}
+}
@@ -3,6 +3,9 @@ package knight
 import (
 	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"sync"
 )
 
 // Variable represents a variable within Knight code.
@@ -10,6 +13,10 @@ import (
 // Variables are created via the NewVariable function, which ensures that each variable of a given
 // name always points to the same underlying Variable struct.
 //
+// Since the Parser calls NewVariable as it parses each variable, looking up variables by name only
+// happens when parsing. Executing a Variable just reads its value field, which is already as cheap
+// as indexing into a slice of "slots" would be.
+//
 // Normally, this type isn't accessible from within Knight programs, as most functions Execute their
 // arguments before interacting with them. However, the `BLOCK` function has been implemented to
 // just return its argument, unevaluated. (So, variables can be accessed via eg `OUTPUT BLOCK foo`.)
@@ -27,8 +34,29 @@ var _ Value = &Variable{}
 // NewVariable function to ensure that all variables of the same name point to the same Variable.
 var variablesMap = make(map[string]*Variable)
 
+// ConcurrentVariables, when true, makes accessing variables safe for concurrent use: NewVariable
+// (and so parsing), executing and assigning Variables, and SnapshotVariables and
+// VariableSnapshot.Restore can then all be called from multiple goroutines at once.
+//
+// This is only about variables: Since they're global, Knight programs running concurrently still
+// share them (and so see each other's assignments), and each individual read or write is atomic, but
+// sequences of them (such as `= a + a 1`) aren't. Other global state (such as Stdout, MaxCallDepth,
+// and `XTIMEOUT`'s deadline) isn't protected either. It's false by default, as locking has a cost.
+//
+// It should only be changed while no Knight code is running.
+var ConcurrentVariables = false
+
+// variablesMutex protects variablesMap and the value of every Variable when ConcurrentVariables is
+// enabled.
+var variablesMutex sync.RWMutex
+
 // NewVariable returns the Variable corresponding to name, creating it if it doesn't exist.
 func NewVariable(name string) *Variable {
+	if ConcurrentVariables {
+		variablesMutex.Lock()
+		defer variablesMutex.Unlock()
+	}
+
 	// If the variable already exists, then return it.
 	if variable, ok := variablesMap[name]; ok {
 		return variable
@@ -40,21 +68,100 @@ func NewVariable(name string) *Variable {
 	return variable
 }
 
+// SetVariable assigns value to the variable called name, creating it if it doesn't exist. It's
+// intended for passing data into Knight programs when embedding them: After `SetVariable("config",
+// ...)`, a program can just read `config`. It panics if value is nil.
+//
+// Knight programs can only refer to variables whose names are valid Knight variable names (ie
+// lowercase letters, digits, and underscores, not starting with a digit), so name should be one.
+func SetVariable(name string, value Value) {
+	NewVariable(name).Assign(value)
+}
+
+// LookupVariable returns the value of the variable called name, and whether it's assigned. It's
+// intended for reading results back out of Knight programs when embedding them. Unlike NewVariable,
+// it never creates the variable.
+func LookupVariable(name string) (Value, bool) {
+	if ConcurrentVariables {
+		variablesMutex.RLock()
+		defer variablesMutex.RUnlock()
+	}
+
+	variable, ok := variablesMap[name]
+	if !ok || variable.value == nil {
+		return nil, false
+	}
+
+	return variable.value, true
+}
+
+// VariableNames returns the names of all variables which are currently assigned, sorted by name.
+// They're sorted so that the result doesn't depend on Go's (random) map iteration order, which
+// makes it suitable for golden tests. Use LookupVariable to get their values.
+func VariableNames() []string {
+	if ConcurrentVariables {
+		variablesMutex.RLock()
+		defer variablesMutex.RUnlock()
+	}
+
+	names := make([]string, 0, len(variablesMap))
+	for name, variable := range variablesMap {
+		if variable.value != nil {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// bindArguments assigns each of values to the variables `_1`, `_2`, etc, in order. It's used by
+// extension functions which pass values to code they execute, as Knight blocks can't take
+// arguments.
+func bindArguments(values ...Value) {
+	for i, value := range values {
+		NewVariable(fmt.Sprintf("_%d", i+1)).Assign(value)
+	}
+}
+
 // Execute looks up the last-assigned value for the variable, returning an error if the variable hasn't
 // been assigned yet.
 func (v *Variable) Execute() (Value, error) {
 	// Assign doesn't allow nil to be assigned to v.value, so we can use nil as a marker for
 	// unassigned variables.
-	if v.value == nil {
+	value := v.load()
+	if value == nil {
 		return nil, fmt.Errorf("undefined variable %q encountered", v.name)
 	}
 
-	return v.value, nil
+	return value, nil
 }
 
-// Dump prints a debug representation of the variable to stdout.
-func (v *Variable) Dump() {
-	fmt.Printf("Variable(%s)", v.name)
+// load returns the variable's value (or nil if it's unassigned), locking variablesMutex if
+// ConcurrentVariables is enabled.
+func (v *Variable) load() Value {
+	if ConcurrentVariables {
+		variablesMutex.RLock()
+		defer variablesMutex.RUnlock()
+	}
+
+	return v.value
+}
+
+// store sets the variable's value (which may be nil to unassign it), locking variablesMutex if
+// ConcurrentVariables is enabled.
+func (v *Variable) store(value Value) {
+	if ConcurrentVariables {
+		variablesMutex.Lock()
+		defer variablesMutex.Unlock()
+	}
+
+	v.value = value
+}
+
+// Dump writes a debug representation of the variable to w.
+func (v *Variable) Dump(w io.Writer) {
+	fmt.Fprintf(w, "Variable(%s)", v.name)
 }
 
 // Assign replaces the old value for the variable with the new value. Panics if value is nil.
@@ -63,7 +170,7 @@ func (v *Variable) Assign(value Value) {
 		panic("<INTERNAL BUG> Variable.Assign called with a nil value?")
 	}
 
-	v.value = value
+	v.store(value)
 }
 
 // Conversions: They always return errors, as variables cannot be converted to other types.
@@ -82,3 +189,44 @@ func (_ *Variable) ToBool() (bool, error) {
 func (_ *Variable) ToSlice() ([]Value, error) {
 	return nil, errors.New("Variable doesn't define list conversions")
 }
+
+// VariableSnapshot is a copy of the values of all known variables at some point in time. It can be
+// used to "fork" the state of a Knight program: Take a snapshot, run some code, and then Restore it
+// to undo any assignments the code made.
+//
+// Only the variables' values are copied; the Variables themselves are shared (so code that was
+// parsed before the snapshot still refers to the same Variables afterwards), as are the functions in
+// KnownFunctions and ExtensionFunctions. Since Values are never modified after they're created,
+// sharing the values themselves (eg a List's elements) between the snapshot and the variables is
+// safe.
+type VariableSnapshot map[*Variable]Value
+
+// SnapshotVariables returns a VariableSnapshot of the current values of all known variables.
+func SnapshotVariables() VariableSnapshot {
+	if ConcurrentVariables {
+		variablesMutex.RLock()
+		defer variablesMutex.RUnlock()
+	}
+
+	snapshot := make(VariableSnapshot, len(variablesMap))
+
+	for _, variable := range variablesMap {
+		snapshot[variable] = variable.value
+	}
+
+	return snapshot
+}
+
+// Restore sets every known variable back to the value it had when the snapshot was taken. Variables
+// which were unassigned at the time (including ones created since then) become unassigned again.
+func (s VariableSnapshot) Restore() {
+	if ConcurrentVariables {
+		variablesMutex.Lock()
+		defer variablesMutex.Unlock()
+	}
+
+	for _, variable := range variablesMap {
+		// (If the variable isn't in the snapshot, `s[variable]` is `nil`, ie unassigned.)
+		variable.value = s[variable]
+	}
+}
@@ -0,0 +1,85 @@
+package knight
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// This is mainly useful when run with `go test -race`.
+func TestConcurrentVariables(t *testing.T) {
+	ConcurrentVariables = true
+	defer func() { ConcurrentVariables = false }()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			// Each goroutine uses its own variables, as sequences of reads and writes aren't atomic.
+			input := fmt.Sprintf("input_%d", i)
+			output := fmt.Sprintf("output_%d", i)
+			n := fmt.Sprintf("n_%d", i)
+			sum := fmt.Sprintf("sum_%d", i)
+
+			for j := 0; j < 50; j++ {
+				SetVariable(input, Integer(j))
+
+				_, err := Evaluate(fmt.Sprintf(
+					"; = %[1]s BLOCK IF (< %[2]s 1) 0 (+ %[2]s ; = %[2]s - %[2]s 1 CALL %[1]s) "+
+						"; = %[2]s %[3]s = %[4]s CALL %[1]s",
+					sum, n, input, output))
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+
+				result, ok := LookupVariable(output)
+				if !ok {
+					t.Errorf("%s isn't assigned", output)
+					return
+				}
+
+				if expected := Integer(j * (j + 1) / 2); result != expected {
+					t.Errorf("expected %d, got %#v", expected, result)
+					return
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func TestVariableSnapshot(t *testing.T) {
+	SetVariable("snapshot_a", Integer(1))
+	SetVariable("snapshot_list", List{Integer(1), Integer(2)})
+	snapshot := SnapshotVariables()
+
+	if _, err := Evaluate(`; = snapshot_a 2 ; = snapshot_list +snapshot_list 3 : = snapshot_new "x"`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Assigning after the snapshot doesn't affect it.
+	if value, _ := LookupVariable("snapshot_a"); value != Integer(2) {
+		t.Errorf("expected snapshot_a to be 2 before restoring, got %#v", value)
+	}
+
+	snapshot.Restore()
+
+	if value, _ := LookupVariable("snapshot_a"); value != Integer(1) {
+		t.Errorf("expected snapshot_a to be restored to 1, got %#v", value)
+	}
+
+	if value, _ := LookupVariable("snapshot_list"); !reflect.DeepEqual(value, List{Integer(1), Integer(2)}) {
+		t.Errorf("expected snapshot_list to be restored to [1, 2], got %s", dumpToString(value))
+	}
+
+	// Variables created after the snapshot become unassigned again.
+	if value, ok := LookupVariable("snapshot_new"); ok {
+		t.Errorf("expected snapshot_new to be unassigned, got %#v", value)
+	}
+}
@@ -1,9 +1,15 @@
 package main
 
 import (
+	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"runtime"
+	"runtime/pprof"
 
 	"github.com/knight-lang/go/knight"
 )
@@ -15,35 +21,191 @@ func printAndExit(fmtStr string, rest ...any) {
 	os.Exit(1)
 }
 
-// usage prints the usage and exits.
-func usage() {
-	printAndExit("usage: %s (-e 'expr' | -f file)", os.Args[0])
+// options are the command-line options, as returned by parseArgs.
+type options struct {
+	expression  string   // the program given via `-e` ("-" means to read it from stdin).
+	path        string   // the program file to run, given via `-f` or as the first argument.
+	cpuProfile  string   // where to write a CPU profile, if anywhere.
+	heapProfile string   // where to write a heap profile, if anywhere.
+	input       string   // the file to read the program's input from, instead of stdin.
+	args        []string // the arguments to give to the program via `XARGS`.
+}
+
+// newFlagSet returns the flags we accept, which are stored into opts when they're parsed.
+func newFlagSet(name string, opts *options) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.StringVar(&opts.expression, "e", "", "the Knight `expr` to run (or - to read it from stdin)")
+	flags.StringVar(&opts.path, "f", "", "the Knight program `file` to run")
+	flags.StringVar(&opts.cpuProfile, "prof", "", "write a CPU profile of running the program to `file`")
+	flags.StringVar(&opts.heapProfile, "mem", "", "write a heap profile to `file` after running the program")
+	flags.StringVar(&opts.input, "input", "", "read the program's input from `file` instead of stdin")
+	return flags
+}
+
+// parseArgs parses the command-line arguments (including the name we were invoked as, like
+// os.Args), and returns the options they specify. An error is returned if they're invalid.
+//
+// Scripts can be run as executables via a shebang line (eg `#!/usr/local/bin/knight -f`, or
+// `#!/usr/bin/env knight`), which is fine for the parser as `#` starts a comment. However, the
+// kernel invokes us with the script's path after the shebang's arguments, followed by whatever
+// arguments the script was run with; so, extra arguments are allowed after the program.
+//
+// The extra arguments are given to the program via `XARGS`. They're everything after the flags
+// (eg `knight -e 'DUMP XARGS' a b`), except the script's path if it wasn't given via `-f` (eg
+// `knight script.kn a b`); either way, `XARGS` is `["a", "b"]`. (As usual for the flag package,
+// flags must come before the arguments, and `--` can be used if the first argument starts with
+// `-`.)
+func parseArgs(argv []string) (*options, error) {
+	var opts options
+	flags := newFlagSet(argv[0], &opts)
+	flags.SetOutput(io.Discard) // (Errors are printed by usage instead.)
+
+	if err := flags.Parse(argv[1:]); err != nil {
+		return nil, err
+	}
+
+	opts.args = flags.Args()
+
+	if opts.expression != "" && opts.path != "" {
+		return nil, errors.New("-e and -f can't both be given")
+	}
+
+	if opts.expression == "" && opts.path == "" {
+		if flags.NArg() == 0 {
+			return nil, errors.New("no program given")
+		}
+
+		opts.path = flags.Arg(0)
+		opts.args = flags.Args()[1:]
+	}
+
+	return &opts, nil
+}
+
+// usage prints err (unless it's from `-h`), and the usage (including a description of each flag),
+// then exits.
+func usage(err error) {
+	if !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "usage: %s [-prof file] [-mem file] [-input file] (-e 'expr' | -e - | -f file | file)\n",
+		os.Args[0])
+
+	flags := newFlagSet(os.Args[0], &options{})
+	flags.SetOutput(os.Stderr)
+	flags.PrintDefaults()
+	os.Exit(1)
+}
+
+// readProgram reads the contents of the program file at path, exiting if it can't be read.
+func readProgram(path string) string {
+	programBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		printAndExit("[FATAL] Couldn't read file contents: %s", err)
+	}
+
+	return string(programBytes)
+}
+
+// readStdinProgram reads the program from stdin (for `-e -`), exiting if it can't be read. This
+// uses up stdin, so `PROMPT` will just return `NULL`, unless `-input` is used to give the program
+// its input from somewhere else.
+func readStdinProgram() string {
+	programBytes, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		printAndExit("[FATAL] Couldn't read program from stdin: %s", err)
+	}
+
+	return string(programBytes)
+}
+
+// useInput makes `PROMPT` (and the other functions which read input) read from the file at path
+// instead of stdin, exiting if it can't be opened.
+//
+// This is mainly for use with `-e -`, which reads the program itself from stdin: The program's input
+// can be given via another file descriptor, such as `knight -e - -input /dev/fd/3 3<input.txt`.
+// (There's no default file descriptor for this, as the Go runtime may use any descriptor that
+// wasn't passed to us for its own files.)
+func useInput(path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		printAndExit("[FATAL] Couldn't open input: %s", err)
+	}
+
+	knight.Stdin = bufio.NewReader(file)
+}
+
+// writeHeapProfile writes a heap profile to the file at path, exiting if it can't be written.
+func writeHeapProfile(path string) {
+	file, err := os.Create(path)
+	if err != nil {
+		printAndExit("[FATAL] Couldn't create heap profile: %s", err)
+	}
+	defer file.Close()
+
+	runtime.GC() // Get up-to-date statistics, as the heap profile is only updated by the GC.
+	if err := pprof.WriteHeapProfile(file); err != nil {
+		printAndExit("[FATAL] Couldn't write heap profile: %s", err)
+	}
 }
 
 func main() {
-	// We expect exactly three arguments: The program name, `-e`/`-f`, and the expression/filename.
-	if len(os.Args) != 3 {
-		usage()
+	opts, err := parseArgs(os.Args)
+	if err != nil {
+		usage(err)
 	}
 
 	var program string
-	switch os.Args[1] {
-	case "-e":
-		program = os.Args[2]
+	switch {
+	case opts.expression == "-":
+		program = readStdinProgram()
+	case opts.expression != "":
+		program = opts.expression
+	default:
+		program = readProgram(opts.path)
+	}
 
-	case "-f":
-		programBytes, err := ioutil.ReadFile(os.Args[2])
+	knight.Args = opts.args
+
+	if opts.input != "" {
+		useInput(opts.input)
+	}
+
+	// Profiling is only done while the program runs, so that parsing the arguments isn't included.
+	// `QUIT` returns an error instead of exiting immediately, so that the profiles are still written
+	// if the program uses it.
+	knight.QuitMode = knight.ReturnError
+
+	if opts.cpuProfile != "" {
+		file, err := os.Create(opts.cpuProfile)
 		if err != nil {
-			printAndExit("[FATAL] Couldn't read file contents: %s", err)
+			printAndExit("[FATAL] Couldn't create CPU profile: %s", err)
 		}
-		program = string(programBytes)
 
-	default:
-		usage()
+		if err := pprof.StartCPUProfile(file); err != nil {
+			printAndExit("[FATAL] Couldn't start CPU profile: %s", err)
+		}
+	}
+
+	_, err = knight.Evaluate(program)
+
+	if opts.cpuProfile != "" {
+		pprof.StopCPUProfile()
+	}
+
+	if opts.heapProfile != "" {
+		writeHeapProfile(opts.heapProfile)
+	}
+
+	// If the program used `QUIT`, exit with its status.
+	var quitError *knight.QuitError
+	if errors.As(err, &quitError) {
+		os.Exit(quitError.Code)
 	}
 
-	// Run the program; if there's a problem, print out the error and abort.
-	if _, err := knight.Evaluate(program); err != nil {
+	// If there was a problem running the program, print out the error and abort.
+	if err != nil {
 		printAndExit("%s", err)
 	}
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	for _, test := range []struct {
+		argv     []string
+		expected options
+	}{
+		// `#!/usr/bin/env knight`
+		{[]string{"knight", "script.kn", "a", "b"},
+			options{path: "script.kn", args: []string{"a", "b"}}},
+
+		// `#!/usr/local/bin/knight -f`
+		{[]string{"/usr/local/bin/knight", "-f", "script.kn", "a", "b"},
+			options{path: "script.kn", args: []string{"a", "b"}}},
+
+		{[]string{"knight", "-f", "script.kn"}, options{path: "script.kn", args: []string{}}},
+		{[]string{"knight", "script.kn"}, options{path: "script.kn", args: []string{}}},
+		{[]string{"knight", "-e", "DUMP XARGS", "a"}, options{expression: "DUMP XARGS", args: []string{"a"}}},
+		{[]string{"knight", "-e", "-", "-input", "in.txt"},
+			options{expression: "-", input: "in.txt", args: []string{}}},
+		{[]string{"knight", "-prof", "cpu", "-mem", "heap", "--", "-script.kn", "-a"},
+			options{path: "-script.kn", cpuProfile: "cpu", heapProfile: "heap", args: []string{"-a"}}},
+	} {
+		opts, err := parseArgs(test.argv)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.argv, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(*opts, test.expected) {
+			t.Errorf("%q: expected %+v, got %+v", test.argv, test.expected, *opts)
+		}
+	}
+}
+
+func TestParseArgsErrors(t *testing.T) {
+	for _, argv := range [][]string{
+		{"knight"},
+		{"knight", "-e", "1", "-f", "script.kn"},
+		{"knight", "-unknown", "script.kn"},
+		{"knight", "-f"},
+	} {
+		if _, err := parseArgs(argv); err == nil {
+			t.Errorf("%q: expected an error", argv)
+		}
+	}
+}